@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+// attachmentKey returns a new, unguessable storage key for one of an item's
+// attachments. Keys are not derived from the content so deleting one
+// attachment never removes a file another still refers to.
+func attachmentKey(itemID int64) (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("attachments/%d/%s", itemID, hex.EncodeToString(b)), nil
+}
+
+// deleteStoredFiles removes files whose records have already been deleted.
+// Failures only leave orphaned files behind, so they are logged rather than
+// reported to the client.
+func (app *application) deleteStoredFiles(keys ...string) {
+	for _, key := range keys {
+		err := app.storage.Delete(context.Background(), key)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"key": key,
+			})
+		}
+	}
+}
+
+func (app *application) handleCreateItemAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		upload, err := app.readUpload(w, r, "file", app.config.attachments.maxBytes)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		sum := sha256.Sum256(upload.Data)
+		user := app.contextGetUser(r)
+
+		attachment := &data.ItemAttachment{
+			ItemID:      id,
+			Filename:    data.CleanAttachmentFilename(upload.Filename),
+			ContentType: data.AttachmentContentType(upload.ContentType, upload.Filename),
+			Size:        int64(len(upload.Data)),
+			Checksum:    hex.EncodeToString(sum[:]),
+			UploadedBy:  &user.ID,
+		}
+
+		v := validator.New()
+
+		// Clients may send the SHA-256 of the file so corruption in transit is
+		// caught before anything is stored.
+		if expected := r.Header.Get("X-Checksum-Sha256"); expected != "" {
+			v.Check(strings.EqualFold(expected, attachment.Checksum), "checksum", "does not match the uploaded file")
+		}
+
+		if data.ValidateItemAttachment(v, attachment, upload.Data); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		attachment.StorageKey, err = attachmentKey(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.storage.Put(r.Context(), attachment.StorageKey, bytes.NewReader(upload.Data), attachment.Size, attachment.ContentType)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.models.ItemAttachments.Insert(attachment)
+		if err != nil {
+			app.deleteStoredFiles(attachment.StorageKey)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/items/%d/attachments/%d", id, attachment.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleListItemAttachments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		attachments, err := app.models.ItemAttachments.GetAllForItem(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"attachments": attachments}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowItemAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		attachmentID, err := app.readInt64Param(r, "attachment")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		attachment, err := app.models.ItemAttachments.Get(id, attachmentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		w.Header().Set("X-Checksum-Sha256", attachment.Checksum)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		app.serveObject(w, r, attachment.StorageKey, attachment.Filename, attachment.ContentType, attachment.Checksum)
+	}
+}
+
+func (app *application) handleDeleteItemAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		attachmentID, err := app.readInt64Param(r, "attachment")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		attachment, err := app.models.ItemAttachments.Delete(id, attachmentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.background(func() {
+			app.deleteStoredFiles(attachment.StorageKey)
+		})
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "attachment successfully deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+// handleListAttributes lists every attribute definition or, given category,
+// the definitions that apply to items in it, including global and inherited
+// ones. category=0 gives those for items without a category.
+func (app *application) handleListAttributes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		category := app.readInt(qs, "category", 0, v)
+		v.Check(category >= 0, "category", "must not be negative")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		var definitions []*data.AttributeDefinition
+		var err error
+
+		switch {
+		case !qs.Has("category"):
+			definitions, err = app.models.Attributes.GetAll()
+		case category == 0:
+			definitions, err = app.models.Attributes.ForCategory(nil)
+		default:
+			id := int64(category)
+			definitions, err = app.models.Attributes.ForCategory(&id)
+		}
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"attributes": definitions}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleCreateAttribute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Name       string   `json:"name"`
+			Type       string   `json:"type"`
+			Unit       string   `json:"unit"`
+			Required   bool     `json:"required"`
+			EnumValues []string `json:"enumValues"`
+			Min        *float64 `json:"min"`
+			Max        *float64 `json:"max"`
+			Category   *int64   `json:"category"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		def := &data.AttributeDefinition{
+			Name:       requestPayload.Name,
+			Type:       requestPayload.Type,
+			Unit:       requestPayload.Unit,
+			Required:   requestPayload.Required,
+			EnumValues: requestPayload.EnumValues,
+			Min:        requestPayload.Min,
+			Max:        requestPayload.Max,
+			Category:   requestPayload.Category,
+		}
+
+		v := validator.New()
+
+		if data.ValidateAttributeDefinition(v, def); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Attributes.Insert(def)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateAttributeName):
+				v.AddError("name", "is already defined for this category")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownCategory):
+				v.AddError("category", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/attributes/%d", def.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"attribute": def}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowAttribute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		def, err := app.models.Attributes.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"attribute": def}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleUpdateAttribute changes the constraints of an attribute. Its name,
+// type and category can't be changed; see data.AttributeModel.Update.
+func (app *application) handleUpdateAttribute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		def, err := app.models.Attributes.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Unit       *string  `json:"unit"`
+			Required   *bool    `json:"required"`
+			EnumValues []string `json:"enumValues"`
+			Min        *float64 `json:"min"`
+			Max        *float64 `json:"max"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if requestPayload.Unit != nil {
+			def.Unit = *requestPayload.Unit
+		}
+
+		if requestPayload.Required != nil {
+			def.Required = *requestPayload.Required
+		}
+
+		if requestPayload.EnumValues != nil {
+			def.EnumValues = requestPayload.EnumValues
+		}
+
+		if requestPayload.Min != nil {
+			def.Min = requestPayload.Min
+		}
+
+		if requestPayload.Max != nil {
+			def.Max = requestPayload.Max
+		}
+
+		v := validator.New()
+
+		if data.ValidateAttributeDefinition(v, def); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Attributes.Update(def)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"attribute": def}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleDeleteAttribute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		err = app.models.Attributes.Delete(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
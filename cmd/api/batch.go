@@ -0,0 +1,410 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+const maxBatchOperations = 500
+
+const (
+	batchCreate    = "create"
+	batchUpdate    = "update"
+	batchArchive   = "archive"
+	batchUnarchive = "unarchive"
+	batchDelete    = "delete"
+)
+
+type batchOperation struct {
+	Op   string     `json:"op"`
+	ID   int64      `json:"id"`
+	Item *itemPatch `json:"item"`
+
+	// item is the item as the operation will write it, once checked.
+	item *data.Item
+}
+
+type batchResult struct {
+	Index  int               `json:"index"`
+	Op     string            `json:"op"`
+	ID     int64             `json:"id,omitempty"`
+	Status string            `json:"status"`
+	Item   *data.Item        `json:"item,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// errBatchFailed marks an operation that failed for a reason reported in its
+// result, as opposed to an unexpected error.
+var errBatchFailed = errors.New("batch operation failed")
+
+func validateBatchOperation(v *validator.Validator, op *batchOperation) {
+	v.Check(validator.PermittedValue(op.Op, batchCreate, batchUpdate, batchArchive, batchUnarchive, batchDelete), "op", "must be one of create, update, archive, unarchive or delete")
+
+	switch op.Op {
+	case batchCreate:
+		v.Check(op.ID == 0, "id", "must not be provided when creating an item")
+		v.Check(op.Item != nil, "item", "must be provided")
+	case batchUpdate:
+		v.Check(op.ID > 0, "id", "must be provided")
+		v.Check(op.Item != nil, "item", "must be provided")
+	case batchArchive, batchUnarchive, batchDelete:
+		v.Check(op.ID > 0, "id", "must be provided")
+		v.Check(op.Item == nil, "item", "must not be provided")
+	}
+}
+
+// batchState follows the items touched by the operations of a batch, so an
+// operation is checked and written against the item as the operations before
+// it leave it rather than as it was stored when the batch began.
+type batchState struct {
+	items     map[int64]*data.Item
+	deleted   map[int64]bool
+	updatedAt map[int64]time.Time
+}
+
+func newBatchState() *batchState {
+	return &batchState{
+		items:     make(map[int64]*data.Item),
+		deleted:   make(map[int64]bool),
+		updatedAt: make(map[int64]time.Time),
+	}
+}
+
+// prepareBatchOperation checks a single operation without writing anything,
+// setting op.item to the item it will write. It returns errBatchFailed for
+// failures described by result and any other error for unexpected failures.
+func (app *application) prepareBatchOperation(tx *data.ItemTx, op *batchOperation, state *batchState, result *batchResult) error {
+	v := validator.New()
+
+	fail := func() error {
+		result.Status = "failed"
+		result.Errors = v.Errors
+		return errBatchFailed
+	}
+
+	if op.Op == batchCreate {
+		item := &data.Item{}
+		op.Item.apply(item)
+
+		schema, err := tx.AttributeSchema(item.Category)
+		if err != nil {
+			return err
+		}
+
+		if data.ValidateItem(v, item, app.models.Currencies.Registry, schema); !v.Valid() {
+			return fail()
+		}
+
+		op.item = item
+		return nil
+	}
+
+	current, ok := state.items[op.ID]
+	if !ok && !state.deleted[op.ID] {
+		var err error
+		current, err = tx.Get(op.ID)
+		if err != nil && !errors.Is(err, data.ErrNoRecord) {
+			return err
+		}
+	}
+
+	if current == nil {
+		v.AddError("id", "must reference an existing item")
+		return fail()
+	}
+
+	if op.Op == batchDelete {
+		delete(state.items, op.ID)
+		state.deleted[op.ID] = true
+		return nil
+	}
+
+	// The patch merges into the attributes map, which must not be shared
+	// with the item as an earlier operation writes it.
+	item := *current
+	item.Attributes = make(data.ItemAttributes, len(current.Attributes))
+	for name, value := range current.Attributes {
+		item.Attributes[name] = value
+	}
+
+	switch op.Op {
+	case batchUpdate:
+		op.Item.apply(&item)
+
+		schema, err := tx.AttributeSchema(item.Category)
+		if err != nil {
+			return err
+		}
+
+		if data.ValidateItem(v, &item, app.models.Currencies.Registry, schema); !v.Valid() {
+			return fail()
+		}
+	case batchArchive:
+		item.Archived = true
+	case batchUnarchive:
+		item.Archived = false
+	}
+
+	op.item = &item
+	state.items[op.ID] = &item
+	return nil
+}
+
+// writeBatchOperation writes an operation checked by prepareBatchOperation
+// inside tx, filling in result. It returns errBatchFailed for failures
+// described by the result and any other error for unexpected failures.
+func (app *application) writeBatchOperation(tx *data.ItemTx, op *batchOperation, state *batchState, userID int64, result *batchResult) error {
+	v := validator.New()
+
+	fail := func() error {
+		result.Status = "failed"
+		result.Errors = v.Errors
+		return errBatchFailed
+	}
+
+	if op.Op == batchCreate {
+		err := tx.Insert(op.item, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrUnknownSupplier):
+				v.AddError("supplier", "must reference an existing supplier")
+				return fail()
+			case errors.Is(err, data.ErrUnknownCategory):
+				v.AddError("category", "must reference an existing category")
+				return fail()
+			default:
+				return err
+			}
+		}
+
+		app.setPriceDisplay(op.item)
+		app.setImageURLs(op.item)
+
+		result.ID = op.item.ID
+		result.Item = op.item
+		result.Status = "created"
+		return nil
+	}
+
+	if op.Op == batchDelete {
+		err := tx.Delete(op.ID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				v.AddError("id", "must reference an existing item")
+				return fail()
+			default:
+				return err
+			}
+		}
+
+		result.Status = "deleted"
+		return nil
+	}
+
+	// An earlier operation on the same item has moved its updated_at on.
+	item := op.item
+	if updatedAt, ok := state.updatedAt[item.ID]; ok {
+		item.UpdatedAt = updatedAt
+	}
+
+	err := tx.Update(item, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownSupplier):
+			v.AddError("supplier", "must reference an existing supplier")
+			return fail()
+		case errors.Is(err, data.ErrUnknownCategory):
+			v.AddError("category", "must reference an existing category")
+			return fail()
+		case errors.Is(err, data.ErrEditConflict):
+			v.AddError("id", "was changed by another request, please try again")
+			return fail()
+		default:
+			return err
+		}
+	}
+
+	state.updatedAt[item.ID] = item.UpdatedAt
+
+	app.setPriceDisplay(item)
+	app.setImageURLs(item)
+
+	switch op.Op {
+	case batchUpdate:
+		result.Status = "updated"
+	case batchArchive:
+		result.Status = "archived"
+	case batchUnarchive:
+		result.Status = "unarchived"
+	}
+
+	result.Item = item
+	return nil
+}
+
+// handleBatchItems applies a list of item operations. By default every
+// operation is checked first, and if any fails validation none are written and
+// all the failures are reported together. The writes then run in a single
+// transaction and the first failure rolls every operation back. With
+// ?atomic=false each operation is checked and committed or fails on its own.
+func (app *application) handleBatchItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Operations []*batchOperation `json:"operations"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		atomic := app.readString(r.URL.Query(), "atomic", "true")
+		v.Check(validator.PermittedValue(atomic, "true", "false"), "atomic", "must be true or false")
+
+		v.Check(len(requestPayload.Operations) > 0, "operations", "must be provided")
+		v.Check(len(requestPayload.Operations) <= maxBatchOperations, "operations", fmt.Sprintf("must not contain more than %d entries", maxBatchOperations))
+
+		for i, op := range requestPayload.Operations {
+			if op == nil {
+				v.AddError(fmt.Sprintf("operations[%d]", i), "must be an object")
+				continue
+			}
+
+			opV := validator.New()
+			validateBatchOperation(opV, op)
+			for key, message := range opV.Errors {
+				v.AddError(fmt.Sprintf("operations[%d].%s", i, key), message)
+			}
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID := app.contextGetUser(r).ID
+		results := make([]*batchResult, len(requestPayload.Operations))
+
+		for i, op := range requestPayload.Operations {
+			results[i] = &batchResult{Index: i, Op: op.Op, ID: op.ID}
+		}
+
+		if atomic == "false" {
+			for i, op := range requestPayload.Operations {
+				tx, err := app.models.Items.Begin(3 * time.Second)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+
+				state := newBatchState()
+
+				err = app.prepareBatchOperation(tx, op, state, results[i])
+				if err == nil {
+					err = app.writeBatchOperation(tx, op, state, userID, results[i])
+				}
+				if err == nil {
+					err = tx.Commit()
+				}
+				tx.Rollback()
+
+				if err != nil && !errors.Is(err, errBatchFailed) {
+					app.errorLog(r, err)
+					results[i].Status = "failed"
+					results[i].Item = nil
+					results[i].Errors = map[string]string{"error": "the server encountered a problem and could not process the operation"}
+				}
+			}
+
+			err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		tx, err := app.models.Items.Begin(30 * time.Second)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		state := newBatchState()
+		failed := false
+
+		for i, op := range requestPayload.Operations {
+			err := app.prepareBatchOperation(tx, op, state, results[i])
+			if err != nil && !errors.Is(err, errBatchFailed) {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			failed = failed || err != nil
+		}
+
+		if failed {
+			for _, result := range results {
+				if result.Status == "" {
+					result.Status = "skipped"
+				}
+			}
+
+			err = app.writeJSON(w, http.StatusUnprocessableEntity, envelope{"results": results}, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		for i, op := range requestPayload.Operations {
+			err := app.writeBatchOperation(tx, op, state, userID, results[i])
+			if err == nil {
+				continue
+			}
+
+			if !errors.Is(err, errBatchFailed) {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			// The transaction is aborted, so nothing before the failure is
+			// kept and nothing after it is attempted.
+			for _, result := range results[:i] {
+				result.Status = "rolled_back"
+				result.Item = nil
+				if result.Op == batchCreate {
+					result.ID = 0
+				}
+			}
+			for _, result := range results[i+1:] {
+				result.Status = "skipped"
+			}
+
+			err = app.writeJSON(w, http.StatusUnprocessableEntity, envelope{"results": results}, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleListCategories() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		categories, err := app.models.Categories.GetTree()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleCreateCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Name   string `json:"name"`
+			Parent *int64 `json:"parent"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		category := &data.Category{
+			Name:   requestPayload.Name,
+			Parent: requestPayload.Parent,
+		}
+
+		v := validator.New()
+
+		if data.ValidateCategory(v, category); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Categories.Insert(category)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateCategoryName):
+				v.AddError("name", "a category with this name already exists under the same parent")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownParent):
+				v.AddError("parent", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/categories/%d", category.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleShowCategory returns a category along with its path from the top of
+// the tree, for breadcrumbs.
+func (app *application) handleShowCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		path, err := app.models.Categories.GetPath(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"category": path[len(path)-1], "path": path}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleUpdateCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		category, err := app.models.Categories.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Name *string `json:"name"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if requestPayload.Name != nil {
+			category.Name = *requestPayload.Name
+		}
+
+		v := validator.New()
+
+		if data.ValidateCategory(v, category); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Categories.Update(category)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateCategoryName):
+				v.AddError("name", "a category with this name already exists under the same parent")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleMoveCategory moves a category under a new parent, or to the top
+// level when parent is null, and to a position among its new siblings.
+// Leaving parent out keeps the current one, which reorders the category, and
+// leaving position out puts it last.
+func (app *application) handleMoveCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		category, err := app.models.Categories.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Parent   nullableID `json:"parent"`
+			Position *int       `json:"position"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		parent := category.Parent
+		if requestPayload.Parent.Set {
+			parent = requestPayload.Parent.Value
+		}
+
+		position := -1
+		if requestPayload.Position != nil {
+			position = *requestPayload.Position
+		}
+
+		v := validator.New()
+
+		v.Check(requestPayload.Position == nil || position >= 0, "position", "must not be negative")
+		v.Check(parent == nil || *parent != category.ID, "parent", "must not be the category itself")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Categories.Move(category, parent, position)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrUnknownParent):
+				v.AddError("parent", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrCategoryCycle):
+				v.AddError("parent", "must not be a subcategory of the category being moved")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrDuplicateCategoryName):
+				v.AddError("parent", "already has a category with this name")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleDeleteCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		err = app.models.Categories.Delete(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			case errors.Is(err, data.ErrCategoryInUse):
+				app.recordInUseResponse(w, r, "the category still has subcategories or attribute definitions, or is assigned to one or more items")
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+)
+
+func (app *application) handleListCurrencies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currencies, err := app.models.Currencies.GetAll()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"currencies": currencies}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// setPriceDisplay fills in the item's price formatted for display in its
+// currency. It is left empty when the currency isn't supported.
+func (app *application) setPriceDisplay(item *data.Item) {
+	currency, ok := app.models.Currencies.Registry.Lookup(item.Currency)
+	if !ok {
+		return
+	}
+
+	item.PriceDisplay = data.FormatPrice(item.Price.Amount, currency)
+}
@@ -81,3 +81,7 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "user account does not have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) recordInUseResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
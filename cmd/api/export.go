@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+	"github.com/vmx-pso/item-service/internal/xlsx"
+)
+
+// exportColumns are the columns of CSV and XLSX exports. They match the
+// columns accepted by the import endpoint.
+var exportColumns = []string{"id", "name", "model", "supplier", "price", "currency", "tags", "notes", "archived", "created_at", "updated_at"}
+
+type itemExporter interface {
+	write(item *data.Item) error
+	flush() error
+	close() error
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) (*csvExporter, error) {
+	e := &csvExporter{w: csv.NewWriter(w)}
+	return e, e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) write(item *data.Item) error {
+	return e.w.Write([]string{
+		strconv.FormatInt(item.ID, 10),
+		csvText(item.Name),
+		csvText(item.Model),
+		strconv.FormatInt(item.Supplier, 10),
+		item.Price.Amount.String(),
+		item.Currency,
+		csvText(strings.Join(item.Tags, ", ")),
+		csvText(item.Notes),
+		strconv.FormatBool(item.Archived),
+		item.CreatedAt.Format(time.RFC3339),
+		item.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+// csvFormulaPrefixes are the characters that make spreadsheet applications
+// read a CSV cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvText neutralises a text cell that a spreadsheet would otherwise run as a
+// formula by prefixing it with an apostrophe. The import endpoint removes the
+// apostrophe again; see csvImportText.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvImportText undoes csvText.
+func csvImportText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) close() error {
+	return e.flush()
+}
+
+type xlsxExporter struct {
+	w *xlsx.Writer
+}
+
+func newXLSXExporter(w io.Writer) (*xlsxExporter, error) {
+	xw, err := xlsx.NewWriter(w, "Items")
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = column
+	}
+
+	return &xlsxExporter{w: xw}, xw.WriteRow(header...)
+}
+
+func (e *xlsxExporter) write(item *data.Item) error {
+	return e.w.WriteRow(
+		item.ID,
+		item.Name,
+		item.Model,
+		item.Supplier,
+		xlsx.Number(item.Price.Amount.String()),
+		item.Currency,
+		strings.Join(item.Tags, ", "),
+		item.Notes,
+		item.Archived,
+		item.CreatedAt.Format(time.RFC3339),
+		item.UpdatedAt.Format(time.RFC3339),
+	)
+}
+
+func (e *xlsxExporter) flush() error {
+	return e.w.Flush()
+}
+
+func (e *xlsxExporter) close() error {
+	return e.w.Close()
+}
+
+// ndjsonExporter writes one item per line in the same JSON form as the item
+// endpoints.
+type ndjsonExporter struct {
+	app *application
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) write(item *data.Item) error {
+	e.app.setPriceDisplay(item)
+	e.app.setImageURLs(item)
+	return e.enc.Encode(item)
+}
+
+func (e *ndjsonExporter) flush() error {
+	return nil
+}
+
+func (e *ndjsonExporter) close() error {
+	return nil
+}
+
+// handleExportItems streams every item matching the list endpoint's filters
+// and sort as CSV, XLSX or NDJSON. Rows are written as they are read from the
+// database rather than collected first.
+func (app *application) handleExportItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		query := app.readItemQuery(qs, v)
+		sort := app.readItemSort(qs, query, v)
+
+		filters := data.Filters{
+			Sort:         sort,
+			SortSafelist: itemSortSafelistFor(sort),
+		}
+		v.Check(validator.PermittedValue(filters.Sort, filters.SortSafelist...), "sort", "invalid sort value")
+
+		format := app.readString(qs, "format", "csv")
+		v.Check(validator.PermittedValue(format, "csv", "xlsx", "ndjson"), "format", "must be csv, xlsx or ndjson")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err := app.fallBackToFuzzy(qs, &query)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		var exporter itemExporter
+
+		contentTypes := map[string]string{
+			"csv":    "text/csv; charset=utf-8",
+			"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"ndjson": "application/x-ndjson",
+		}
+
+		w.Header().Set("Content-Type", contentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="items-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		switch format {
+		case "csv":
+			exporter, err = newCSVExporter(w)
+		case "xlsx":
+			exporter, err = newXLSXExporter(w)
+		case "ndjson":
+			exporter = &ndjsonExporter{app: app, enc: json.NewEncoder(w)}
+		}
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// An export can take longer than the server's write timeout. From Go
+		// 1.20 the response writer can lift the deadline for this response.
+		if d, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+			d.SetWriteDeadline(time.Time{})
+		}
+
+		flusher, _ := w.(http.Flusher)
+		written := 0
+
+		err = app.models.Items.Stream(r.Context(), query, filters, func(item *data.Item) error {
+			err := exporter.write(item)
+			if err != nil {
+				return err
+			}
+
+			written++
+			if written%500 == 0 && flusher != nil {
+				err = exporter.flush()
+				if err != nil {
+					return err
+				}
+				flusher.Flush()
+			}
+
+			return nil
+		})
+		if err == nil {
+			err = exporter.close()
+		}
+
+		// Once rows have been sent the status can't be changed, so abort the
+		// connection to make sure the client doesn't mistake a partial file
+		// for a complete one.
+		if err != nil {
+			app.errorLog(r, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/vmx-pso/item-service/internal/storage"
+)
+
+// serveObject writes a stored object to the response. The etag should be a
+// value that changes whenever the object's content does; it is quoted here.
+// Range and conditional requests are handled by http.ServeContent when the
+// backend's body is seekable, as it is for both the filesystem and S3 stores,
+// otherwise only If-None-Match is honoured.
+func (app *application) serveObject(w http.ResponseWriter, r *http.Request, key, name, contentType, etag string) {
+	obj, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer obj.Close()
+
+	if contentType == "" {
+		contentType = obj.ContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if etag != "" {
+		w.Header().Set("ETag", strconv.Quote(etag))
+	}
+
+	if body, ok := obj.Body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, obj.ModTime, body)
+		return
+	}
+
+	if etag != "" && r.Header.Get("If-None-Match") == strconv.Quote(etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if !obj.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_, err = io.Copy(w, obj.Body)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"key": key})
+	}
+}
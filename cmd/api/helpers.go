@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/vmx-pso/item-service/internal/validator"
@@ -17,11 +19,15 @@ import (
 type envelope map[string]any
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readInt64Param(r, "id")
+}
+
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
@@ -71,6 +77,100 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// nullableID is an optional JSON id that tells a null value, which clears
+// the field, apart from the field being left out.
+type nullableID struct {
+	Set   bool
+	Value *int64
+}
+
+func (n *nullableID) UnmarshalJSON(b []byte) error {
+	n.Set = true
+
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	var id int64
+	if err := json.Unmarshal(b, &id); err != nil || id < 1 {
+		return errors.New("body contains an id that is not a positive integer or null")
+	}
+
+	n.Value = &id
+	return nil
+}
+
+type upload struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// readUpload reads a file sent either as the named field of a
+// multipart/form-data body or as the raw request body. It isn't subject to
+// the readJSON limit; maxBytes bounds the whole request body instead.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int64) (*upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, errors.New("request must have a valid Content-Type header")
+	}
+
+	var u upload
+	var src io.Reader
+
+	if mediaType == "multipart/form-data" {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil, fmt.Errorf("body must contain a %q file field", field)
+			}
+			if err != nil {
+				if err.Error() == "http: request body too large" {
+					return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+				}
+				return nil, err
+			}
+
+			if part.FormName() == field {
+				u.Filename = part.FileName()
+				u.ContentType = part.Header.Get("Content-Type")
+				src = part
+				break
+			}
+		}
+	} else {
+		u.ContentType = mediaType
+		src = r.Body
+
+		_, disposition, err := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		if err == nil {
+			u.Filename = disposition["filename"]
+		}
+	}
+
+	u.Data, err = io.ReadAll(src)
+	if err != nil {
+		if err.Error() == "http: request body too large" {
+			return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+		}
+		return nil, err
+	}
+
+	if len(u.Data) == 0 {
+		return nil, errors.New("body must not be empty")
+	}
+
+	return &u, nil
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.Marshal(data)
 	if err != nil {
@@ -106,6 +206,21 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+func (app *application) readIDList(qs url.Values, key string, v *validator.Validator) []int64 {
+	ids := []int64{}
+
+	for _, value := range app.readCSV(qs, key, []string{}) {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || id < 1 {
+			v.AddError(key, "must be a comma separated list of positive integers")
+			return []int64{}
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	value := qs.Get(key)
 	if value == "" {
@@ -120,6 +235,28 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// readTime reads an RFC 3339 timestamp or a plain YYYY-MM-DD date, which is
+// taken as midnight UTC at the start of that day.
+func (app *application) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	value := qs.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t
+	}
+
+	t, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		v.AddError(key, "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		return defaultValue
+	}
+
+	return t
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 	go func() {
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/images"
+	"github.com/vmx-pso/item-service/internal/storage"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+// imageKey returns the storage key of a content-addressed image. Keys are
+// fanned out under prefixes named after the first two hex digits of their
+// hash.
+func imageKey(name string) string {
+	return "images/" + name[:2] + "/" + name
+}
+
+// saveImage stores an image unless an identical one is already stored.
+func (app *application) saveImage(ctx context.Context, name string, data []byte) error {
+	key := imageKey(name)
+
+	exists, err := storage.Exists(ctx, app.storage, key)
+	if err != nil || exists {
+		return err
+	}
+
+	return app.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), images.ContentType(name))
+}
+
+// saveImageVariant resizes the named original image and stores the result
+// beside it, returning the variant's file name.
+func (app *application) saveImageVariant(ctx context.Context, name string, original []byte, variant images.Variant) (string, error) {
+	variantName := images.VariantName(name, variant)
+
+	resized, err := images.Resize(original, variant)
+	if err != nil {
+		return "", err
+	}
+
+	return variantName, app.saveImage(ctx, variantName, resized)
+}
+
+// generateImageVariants creates every missing variant of the named image.
+func (app *application) generateImageVariants(name string, original []byte) {
+	ctx := context.Background()
+
+	for _, variant := range images.Variants {
+		_, err := app.saveImageVariant(ctx, name, original, variant)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"image":   name,
+				"variant": variant.Name,
+			})
+		}
+	}
+}
+
+// setImageURLs fills in the URLs an item's image and its variants can be
+// downloaded from.
+func (app *application) setImageURLs(item *data.Item) {
+	if item.ImageFile == "" {
+		return
+	}
+
+	base := fmt.Sprintf("/v1/items/%d/image", item.ID)
+
+	item.ImageURLs = map[string]string{"original": base}
+	for _, variant := range images.Variants {
+		item.ImageURLs[variant.Name] = base + "?size=" + variant.Name
+	}
+}
+
+func (app *application) handleUploadItemImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		item, err := app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		upload, err := app.readUpload(w, r, "image", app.config.images.maxBytes)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if upload.ContentType != "" && upload.ContentType != "application/octet-stream" {
+			v.Check(strings.HasPrefix(upload.ContentType, "image/"), "image", "must have an image content type")
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		img, err := images.Sanitise(upload.Data)
+		if err != nil {
+			switch {
+			case errors.Is(err, images.ErrUnsupportedType):
+				v.AddError("image", "must be a JPEG, PNG or GIF image")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, images.ErrInvalidImage):
+				v.AddError("image", "could not be decoded")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, images.ErrTooManyPixels):
+				v.AddError("image", "has too many pixels")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.saveImage(r.Context(), img.Name, img.Data)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.background(func() {
+			app.generateImageVariants(img.Name, img.Data)
+		})
+
+		item.ImageFile = img.Name
+
+		err = app.models.Items.Update(item, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowItemImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		size := app.readString(r.URL.Query(), "size", "")
+
+		variant, ok := images.LookupVariant(size)
+		if size != "" {
+			v.Check(ok, "size", "invalid size value")
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		item, err := app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !images.ValidName(item.ImageFile) {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		name := item.ImageFile
+
+		if size != "" {
+			name = images.VariantName(item.ImageFile, variant)
+
+			// Variants are normally generated straight after upload, but
+			// create one on demand if that hasn't happened yet.
+			exists, err := storage.Exists(r.Context(), app.storage, imageKey(name))
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			if !exists {
+				original, err := storage.ReadAll(r.Context(), app.storage, imageKey(item.ImageFile))
+				if err != nil {
+					switch {
+					case errors.Is(err, storage.ErrNotFound):
+						app.notFoundResponse(w, r)
+					default:
+						app.serverErrorResponse(w, r, err)
+					}
+					return
+				}
+
+				_, err = app.saveImageVariant(r.Context(), item.ImageFile, original, variant)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+			}
+		}
+
+		// The ETag is the content hash, so clients can revalidate cheaply
+		// and always see a replaced image straight away.
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		app.serveObject(w, r, imageKey(name), name, images.ContentType(name), strings.TrimSuffix(name, path.Ext(name)))
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+const maxImportRows = 5000
+
+var importColumns = []string{"id", "name", "model", "supplier", "price", "currency", "tags", "notes"}
+
+// ignoredImportColumns are written by the CSV export but can't be set by an
+// import. They are accepted and skipped so an export can be edited and
+// imported again.
+var ignoredImportColumns = []string{"archived", "created_at", "updated_at"}
+
+type importRow struct {
+	Row    int               `json:"row"`
+	Action string            `json:"action,omitempty"`
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+
+	item *data.Item
+}
+
+type importSummary struct {
+	Rows    int `json:"rows"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+// readImportCSV reads an uploaded CSV file into a header index, the number of
+// header fields and its data rows. Header names are matched
+// case-insensitively against importColumns. Rows may have a different
+// number of fields to the header, which the caller reports per row.
+func readImportCSV(content []byte) (map[string]int, int, [][]string, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, nil, errors.New("file must contain a header row")
+		}
+		return nil, 0, nil, fmt.Errorf("file is not valid CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if validator.PermittedValue(name, ignoredImportColumns...) {
+			continue
+		}
+
+		if !validator.PermittedValue(name, importColumns...) {
+			return nil, 0, nil, fmt.Errorf("file contains unknown column %q, columns must be from %s", name, strings.Join(importColumns, ", "))
+		}
+
+		if _, ok := columns[name]; ok {
+			return nil, 0, nil, fmt.Errorf("file contains column %q more than once", name)
+		}
+
+		columns[name] = i
+	}
+
+	var records [][]string
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("file is not valid CSV: %w", err)
+		}
+
+		records = append(records, record)
+
+		if len(records) > maxImportRows {
+			return nil, 0, nil, fmt.Errorf("file must not contain more than %d rows", maxImportRows)
+		}
+	}
+
+	return columns, len(header), records, nil
+}
+
+// applyImportRecord copies the non-empty cells of a CSV record onto item.
+// Empty cells leave the item's current value alone, so an update only needs
+// the columns that are changing.
+func (app *application) applyImportRecord(v *validator.Validator, item *data.Item, columns map[string]int, record []string, suppliers map[string]int64) error {
+	for column, i := range columns {
+		value := csvImportText(strings.TrimSpace(record[i]))
+		if value == "" {
+			continue
+		}
+
+		switch column {
+		case "id":
+			id, err := strconv.ParseInt(value, 10, 64)
+			v.Check(err == nil && id > 0, "id", "must be a positive integer")
+			item.ID = id
+		case "name":
+			item.Name = value
+		case "model":
+			item.Model = value
+		case "notes":
+			item.Notes = value
+		case "currency":
+			item.Currency = value
+		case "tags":
+			// Segments left empty by a trailing or doubled separator, as
+			// in "a; ", are dropped.
+			item.Tags = []string{}
+			for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					item.Tags = append(item.Tags, tag)
+				}
+			}
+		case "price":
+			price, err := data.ParsePrice(value)
+			v.Check(err == nil, "price", "must be a valid price")
+			item.Price = price
+		case "supplier":
+			// Suppliers may be given by id or by name.
+			id, ok := suppliers[strings.ToLower(value)]
+			if !ok {
+				supplier, err := app.lookupImportSupplier(value)
+				if err != nil {
+					return err
+				}
+				if supplier != nil {
+					id = supplier.ID
+				}
+				suppliers[strings.ToLower(value)] = id
+			}
+
+			v.Check(id != 0, "supplier", "must reference an existing supplier")
+			item.Supplier = id
+		}
+	}
+
+	return nil
+}
+
+// importAttributeSchema returns the attribute schema for items in category,
+// caching it by category id, with 0 standing for no category.
+func (app *application) importAttributeSchema(tx *data.ItemTx, category *int64, schemas map[int64]data.AttributeSchema) (data.AttributeSchema, error) {
+	var id int64
+	if category != nil {
+		id = *category
+	}
+
+	if schema, ok := schemas[id]; ok {
+		return schema, nil
+	}
+
+	schema, err := tx.AttributeSchema(category)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas[id] = schema
+	return schema, nil
+}
+
+func (app *application) lookupImportSupplier(value string) (*data.Supplier, error) {
+	var supplier *data.Supplier
+	var err error
+
+	id, parseErr := strconv.ParseInt(value, 10, 64)
+	if parseErr == nil {
+		supplier, err = app.models.Suppliers.Get(id)
+	} else {
+		supplier, err = app.models.Suppliers.GetByName(value)
+	}
+
+	switch {
+	case errors.Is(err, data.ErrNoRecord):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+// handleImportItems creates or updates items from the rows of a CSV file. All
+// rows are checked before anything is written and the file is only imported
+// if every row is valid, in a single transaction. With ?dry_run=true the
+// result of the checks is returned without writing anything. ?upsert_key
+// lists the fields, such as supplier,model, that identify an existing item
+// for a row to update instead of creating a new one.
+func (app *application) handleImportItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		dryRun := app.readString(qs, "dry_run", "false")
+		v.Check(validator.PermittedValue(dryRun, "true", "false"), "dry_run", "must be true or false")
+
+		upsertKey := app.readCSV(qs, "upsert_key", []string{})
+		for _, field := range upsertKey {
+			v.Check(validator.PermittedValue(field, data.ItemKeyFields...), "upsert_key", "must only contain "+strings.Join(data.ItemKeyFields, ", "))
+		}
+		v.Check(validator.Unique(upsertKey), "upsert_key", "must not contain duplicate values")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		upload, err := app.readUpload(w, r, "file", app.config.imports.maxBytes)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		columns, width, records, err := readImportCSV(upload.Data)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		for _, field := range upsertKey {
+			_, ok := columns[field]
+			v.Check(ok, "upsert_key", fmt.Sprintf("field %q must be a column of the file", field))
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		tx, err := app.models.Items.Begin(time.Minute)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		rows := make([]*importRow, len(records))
+		summary := importSummary{Rows: len(records)}
+		suppliers := make(map[string]int64)
+		schemas := make(map[int64]data.AttributeSchema)
+		seenKeys := make(map[string]int)
+
+		// Check every row first. Lookups run in the transaction the rows are
+		// later written in, but nothing is written during this pass.
+		for i, record := range records {
+			row := &importRow{Row: i + 2}
+			rows[i] = row
+
+			if len(record) != width {
+				row.Errors = map[string]string{"row": fmt.Sprintf("must have %d fields like the header, not %d", width, len(record))}
+				summary.Failed++
+				continue
+			}
+
+			rowV := validator.New()
+
+			keyItem := &data.Item{}
+			err := app.applyImportRecord(rowV, keyItem, columns, record, suppliers)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			var existing *data.Item
+
+			if len(upsertKey) > 0 && rowV.Valid() {
+				// Duplicates are found by the values GetByKey matches on, so
+				// a supplier given by id and by name is the same key.
+				keyValues := make([]string, len(upsertKey))
+				for j, field := range upsertKey {
+					rowV.Check(strings.TrimSpace(record[columns[field]]) != "", field, "must be provided as part of the upsert key")
+
+					switch field {
+					case "id":
+						keyValues[j] = strconv.FormatInt(keyItem.ID, 10)
+					case "name":
+						keyValues[j] = keyItem.Name
+					case "model":
+						keyValues[j] = keyItem.Model
+					case "supplier":
+						keyValues[j] = strconv.FormatInt(keyItem.Supplier, 10)
+					}
+				}
+
+				key := strings.Join(keyValues, "\x00")
+				if previous, ok := seenKeys[key]; ok {
+					rowV.AddError("row", fmt.Sprintf("has the same upsert key as row %d", previous))
+				}
+				seenKeys[key] = row.Row
+
+				if rowV.Valid() {
+					existing, err = tx.GetByKey(upsertKey, keyItem)
+					switch {
+					case errors.Is(err, data.ErrNoRecord):
+						existing = nil
+					case errors.Is(err, data.ErrAmbiguousItemKey):
+						rowV.AddError("row", "matches more than one existing item")
+					case err != nil:
+						app.serverErrorResponse(w, r, err)
+						return
+					}
+				}
+			}
+
+			if rowV.Valid() {
+				if existing != nil {
+					row.Action = "update"
+					row.ID = existing.ID
+					row.item = existing
+				} else {
+					row.Action = "create"
+					row.item = &data.Item{}
+				}
+
+				err := app.applyImportRecord(rowV, row.item, columns, record, suppliers)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+
+				if row.Action == "create" {
+					row.item.ID = 0
+				}
+
+				schema, err := app.importAttributeSchema(tx, row.item.Category, schemas)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+
+				data.ValidateItem(rowV, row.item, app.models.Currencies.Registry, schema)
+			}
+
+			if !rowV.Valid() {
+				row.Errors = rowV.Errors
+				summary.Failed++
+				continue
+			}
+
+			if row.Action == "create" {
+				summary.Created++
+			} else {
+				summary.Updated++
+			}
+		}
+
+		if dryRun == "true" || summary.Failed > 0 {
+			status := http.StatusOK
+			if dryRun == "false" {
+				status = http.StatusUnprocessableEntity
+			}
+
+			err = app.writeJSON(w, status, envelope{"dry_run": dryRun == "true", "summary": summary, "rows": rows}, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		userID := app.contextGetUser(r).ID
+
+		for _, row := range rows {
+			if row.Action == "create" {
+				err = tx.Insert(row.item, userID)
+			} else {
+				err = tx.Update(row.item, userID)
+			}
+
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrUnknownSupplier):
+					row.Errors = map[string]string{"supplier": "must reference an existing supplier"}
+				case errors.Is(err, data.ErrUnknownCategory):
+					row.Errors = map[string]string{"category": "must reference an existing category"}
+				case errors.Is(err, data.ErrEditConflict):
+					row.Errors = map[string]string{"row": "the matching item was changed by another request, please try again"}
+				default:
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+
+				// Nothing is imported, so rows written before this one
+				// are rolled back and no longer count as created or
+				// updated.
+				summary = importSummary{Rows: len(records), Failed: 1}
+				for _, written := range rows {
+					if written.Action == "create" {
+						written.ID = 0
+					}
+				}
+
+				err = app.writeJSON(w, http.StatusUnprocessableEntity, envelope{"dry_run": false, "summary": summary, "rows": rows}, nil)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			row.ID = row.item.ID
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"dry_run": false, "summary": summary, "rows": rows}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/vmx-pso/item-service/internal/data"
 	"github.com/vmx-pso/item-service/internal/validator"
@@ -12,14 +15,16 @@ import (
 func (app *application) handleCreateItem() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var requestPayload struct {
-			Name      string     `json:"name"`
-			Model     string     `json:"model"`
-			Supplier  int64      `json:"supplier"`
-			Price     data.Price `json:"price"`
-			Currency  int64      `json:"currency"`
-			ImageFile string     `json:"image"`
-			Notes     string     `json:"notes"`
-			Tags      []string   `json:"tags"`
+			Name       string              `json:"name"`
+			Model      string              `json:"model"`
+			Supplier   int64               `json:"supplier"`
+			Category   *int64              `json:"category"`
+			Price      data.Price          `json:"price"`
+			Currency   string              `json:"currency"`
+			ImageFile  string              `json:"image"`
+			Notes      string              `json:"notes"`
+			Tags       []string            `json:"tags"`
+			Attributes data.ItemAttributes `json:"attributes"`
 		}
 
 		err := app.readJSON(w, r, &requestPayload)
@@ -29,26 +34,43 @@ func (app *application) handleCreateItem() http.HandlerFunc {
 		}
 
 		item := &data.Item{
-			Name:      requestPayload.Name,
-			Model:     requestPayload.Model,
-			Supplier:  requestPayload.Supplier,
-			Price:     float64(requestPayload.Price),
-			Currency:  requestPayload.Currency,
-			ImageFile: requestPayload.ImageFile,
-			Notes:     requestPayload.Notes,
-			Tags:      requestPayload.Tags,
+			Name:       requestPayload.Name,
+			Model:      requestPayload.Model,
+			Supplier:   requestPayload.Supplier,
+			Category:   requestPayload.Category,
+			Price:      requestPayload.Price,
+			Currency:   requestPayload.Currency,
+			ImageFile:  requestPayload.ImageFile,
+			Notes:      requestPayload.Notes,
+			Tags:       requestPayload.Tags,
+			Attributes: requestPayload.Attributes,
+		}
+
+		schema, err := app.models.Attributes.Schema(item.Category)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
 		}
 
 		v := validator.New()
 
-		if data.ValidateItem(v, item); !v.Valid() {
+		if data.ValidateItem(v, item, app.models.Currencies.Registry, schema); !v.Valid() {
 			app.failedValidationResponse(w, r, v.Errors)
 			return
 		}
 
-		err = app.models.Items.Insert(item)
+		err = app.models.Items.Insert(item, app.contextGetUser(r).ID)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
+			switch {
+			case errors.Is(err, data.ErrUnknownSupplier):
+				v.AddError("supplier", "must reference an existing supplier")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownCategory):
+				v.AddError("category", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
 			return
 		}
 
@@ -70,6 +92,22 @@ func (app *application) handleShowItem() http.HandlerFunc {
 			return
 		}
 
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		asOf := app.readTime(qs, "as_of", time.Time{}, v)
+
+		expand := app.readCSV(qs, "expand", []string{})
+		for _, field := range expand {
+			v.Check(validator.PermittedValue(field, "supplier", "stock"), "expand", "invalid expand value")
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
 		item, err := app.models.Items.Get(id)
 		if err != nil {
 			switch {
@@ -81,6 +119,41 @@ func (app *application) handleShowItem() http.HandlerFunc {
 			return
 		}
 
+		if !asOf.IsZero() {
+			price, err := app.models.ItemPrices.GetAsOf(item.ID, asOf)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrNoRecord):
+					app.notFoundResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			item.Price = price.Price
+			item.Currency = price.Currency
+		}
+
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		if validator.PermittedValue("supplier", expand...) {
+			item.SupplierDetails, err = app.models.Suppliers.Get(item.Supplier)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		if validator.PermittedValue("stock", expand...) {
+			err = app.setStockSummaries([]*data.Item{item})
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
 		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
@@ -88,6 +161,74 @@ func (app *application) handleShowItem() http.HandlerFunc {
 	}
 }
 
+// itemPatch holds the item fields a client may change. Fields left out of
+// the request are nil and keep their current value.
+type itemPatch struct {
+	Name      *string     `json:"name"`
+	Model     *string     `json:"model"`
+	Supplier  *int64      `json:"supplier"`
+	Category  nullableID  `json:"category"`
+	Price     *data.Price `json:"price"`
+	Currency  *string     `json:"currency"`
+	ImageFile *string     `json:"image"`
+	Notes     *string     `json:"notes"`
+	Tags      []string    `json:"tags"`
+	Archived  *bool       `json:"archived"`
+	// Attributes are merged into the item's: attributes left out keep their
+	// value and those set to null are removed.
+	Attributes data.ItemAttributes `json:"attributes"`
+}
+
+func (p *itemPatch) apply(item *data.Item) {
+	if p.Name != nil {
+		item.Name = *p.Name
+	}
+
+	if p.Model != nil {
+		item.Model = *p.Model
+	}
+
+	if p.Supplier != nil {
+		item.Supplier = *p.Supplier
+	}
+
+	if p.Category.Set {
+		item.Category = p.Category.Value
+	}
+
+	if p.Price != nil {
+		item.Price = *p.Price
+	}
+
+	if p.Currency != nil {
+		item.Currency = *p.Currency
+	}
+
+	if p.ImageFile != nil {
+		item.ImageFile = *p.ImageFile
+	}
+
+	if p.Notes != nil {
+		item.Notes = *p.Notes
+	}
+
+	if p.Tags != nil {
+		item.Tags = p.Tags
+	}
+
+	if p.Archived != nil {
+		item.Archived = *p.Archived
+	}
+
+	if p.Attributes != nil && item.Attributes == nil {
+		item.Attributes = data.ItemAttributes{}
+	}
+
+	for name, value := range p.Attributes {
+		item.Attributes[name] = value
+	}
+}
+
 func (app *application) handleUpdateItem() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := app.readIDParam(r)
@@ -107,17 +248,7 @@ func (app *application) handleUpdateItem() http.HandlerFunc {
 			return
 		}
 
-		var requestPayload struct {
-			Name      *string     `json:"name"`
-			Model     *string     `json:"model"`
-			Supplier  *int64      `json:"supplier"`
-			Price     *data.Price `json:"price"`
-			Currency  *int64      `json:"currency"`
-			ImageFile *string     `json:"image"`
-			Notes     *string     `json:"notes"`
-			Tags      []string    `json:"tags"`
-			Archived  *bool       `json:"archived"`
-		}
+		var requestPayload itemPatch
 
 		err = app.readJSON(w, r, &requestPayload)
 		if err != nil {
@@ -125,76 +256,397 @@ func (app *application) handleUpdateItem() http.HandlerFunc {
 			return
 		}
 
-		if requestPayload.Name != nil {
-			item.Name = *requestPayload.Name
+		requestPayload.apply(item)
+
+		schema, err := app.models.Attributes.Schema(item.Category)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
 		}
 
-		if requestPayload.Model != nil {
-			item.Model = *requestPayload.Model
+		v := validator.New()
+
+		if data.ValidateItem(v, item, app.models.Currencies.Registry, schema); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
 		}
 
-		if requestPayload.Supplier != nil {
-			item.Supplier = *requestPayload.Supplier
+		err = app.models.Items.Update(item, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrUnknownSupplier):
+				v.AddError("supplier", "must reference an existing supplier")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownCategory):
+				v.AddError("category", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
 		}
 
-		if requestPayload.Price != nil {
-			item.Price = float64(*requestPayload.Price)
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
 		}
+	}
+}
 
-		if requestPayload.Currency != nil {
-			item.Currency = *requestPayload.Currency
+func (app *application) handleDeleteItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil || id < 1 {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		err = app.models.Items.Delete(id, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "item moved to trash"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleArchiveItem returns a handler that archives or unarchives an item.
+// Archiving an item that is already archived is not an error.
+func (app *application) handleArchiveItem(archived bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		item, err := app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
 		}
 
-		if requestPayload.ImageFile != nil {
-			item.ImageFile = *requestPayload.ImageFile
+		if item.Archived != archived {
+			item.Archived = archived
+
+			err = app.models.Items.Update(item, app.contextGetUser(r).ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrEditConflict):
+					app.editConflictResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
 		}
 
-		if requestPayload.Notes != nil {
-			item.Notes = *requestPayload.Notes
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
 		}
+	}
+}
+
+var itemFilterSafelist = []string{"id", "name", "model", "notes", "supplier", "supplier_name", "category", "price", "currency", "tags", "archived", "created_at", "updated_at", "attributes"}
+
+var itemSortSafelist = []string{"id", "name", "model", "supplier", "price", "-id", "-name", "-model", "-price", "relevance"}
+
+// itemSortSafelistFor returns the item sort safelist, extended with sort if
+// it orders by a custom attribute, such as -attributes.length.
+func itemSortSafelistFor(sort string) []string {
+	if data.IsAttributeSort(sort) {
+		return append([]string{sort}, itemSortSafelist...)
+	}
+	return itemSortSafelist
+}
+
+// readItemQuery reads the filters shared by the item list and export
+// endpoints from the query string.
+func (app *application) readItemQuery(qs url.Values, v *validator.Validator) data.ItemQuery {
+	query := data.ItemQuery{
+		IDs:          app.readIDList(qs, "ids", v),
+		Name:         app.readString(qs, "name", ""),
+		Supplier:     app.readInt(qs, "supplier", 0, v),
+		SupplierName: app.readString(qs, "supplier_name", ""),
+		Category:     app.readInt(qs, "category", 0, v),
+		Model:        app.readString(qs, "model", ""),
+		Tags:         app.readCSV(qs, "tags", []string{}),
+		AnyTags:      app.readCSV(qs, "tags_any", []string{}),
+		Currency:     strings.ToUpper(app.readString(qs, "currency", "")),
+		PriceMin:     app.readPriceBound(qs, "price_min", v),
+		PriceMax:     app.readPriceBound(qs, "price_max", v),
+		CreatedAfter: app.readTime(qs, "created_after", time.Time{}, v),
+		UpdatedSince: app.readTime(qs, "updated_since", time.Time{}, v),
+		Search:       strings.TrimSpace(qs.Get("q")),
+		Language:     app.readString(qs, "lang", app.config.search.language),
+	}
 
-		if requestPayload.Tags != nil {
-			item.Tags = requestPayload.Tags
+	v.Check(len(query.Search) <= 500, "q", "must not be more than 500 characters long")
+
+	fuzzy := app.readString(qs, "fuzzy", "auto")
+	v.Check(validator.PermittedValue(fuzzy, "auto", "true", "false"), "fuzzy", "must be auto, true or false")
+	query.Fuzzy = fuzzy == "true"
+	v.Check(validator.PermittedValue(query.Language, data.SearchLanguages...), "lang", "must be one of "+strings.Join(data.SearchLanguages, ", "))
+
+	v.Check(len(query.IDs) <= 100, "ids", "must not contain more than 100 values")
+
+	v.Check(query.Category >= 0, "category", "must be a positive integer")
+
+	descendants := app.readString(qs, "include_descendants", "false")
+	v.Check(validator.PermittedValue(descendants, "true", "false"), "include_descendants", "must be true or false")
+	v.Check(query.Category != 0 || descendants == "false", "include_descendants", "must only be used with category")
+	query.IncludeDescendants = descendants == "true"
+
+	for i := range query.Tags {
+		query.Tags[i] = data.NormalizeTag(query.Tags[i])
+	}
+
+	for i := range query.AnyTags {
+		query.AnyTags[i] = data.NormalizeTag(query.AnyTags[i])
+	}
+
+	if query.Currency != "" {
+		currency, ok := app.models.Currencies.Registry.Lookup(query.Currency)
+		v.Check(ok, "currency", "must be a supported ISO 4217 currency code")
+
+		if ok {
+			query.Currency = currency.Code
 		}
+	}
+
+	// Amounts in different currencies can't be compared, so a price range
+	// only makes sense within one.
+	if query.PriceMin != nil || query.PriceMax != nil {
+		v.Check(query.Currency != "", "currency", "must be provided when filtering by price")
+	}
+
+	if query.PriceMin != nil && query.PriceMax != nil {
+		v.Check(query.PriceMin.Cmp(*query.PriceMax) <= 0, "price_max", "must not be less than price_min")
+	}
 
-		if requestPayload.Archived != nil {
-			item.Archived = *requestPayload.Archived
+	if expr := qs.Get("filter"); expr != "" {
+		v.Check(len(expr) <= 1000, "filter", "must not be more than 1000 characters long")
+
+		filter, err := data.ParseItemFilter(expr, itemFilterSafelist, query.Currency != "")
+		if err != nil {
+			v.AddError("filter", err.Error())
 		}
+		query.Filter = filter
+	}
+
+	archived := app.readString(qs, "archived", "false")
+	v.Check(validator.PermittedValue(archived, "true", "false", "all"), "archived", "must be true, false or all")
+
+	// Items asked for by id, or by a filter expression that tests archived,
+	// are returned whether or not they are archived unless the client also
+	// sets the archived parameter.
+	if !qs.Has("archived") && (len(query.IDs) > 0 || (query.Filter != nil && query.Filter.References("archived"))) {
+		archived = "all"
+	}
+
+	if archived != "all" {
+		query.Archived = new(bool)
+		*query.Archived = archived == "true"
+	}
+
+	return query
+}
+
+// fallBackToFuzzy switches a search that no item matches to fuzzy matching,
+// unless the client chose fuzzy=true or fuzzy=false.
+func (app *application) fallBackToFuzzy(qs url.Values, query *data.ItemQuery) error {
+	if query.Search == "" || app.readString(qs, "fuzzy", "auto") != "auto" {
+		return nil
+	}
+
+	matched, err := app.models.Items.Matches(*query)
+	if err != nil {
+		return err
+	}
+
+	query.Fuzzy = !matched
 
+	return nil
+}
+
+// readItemSort reads the sort parameter for item listings, which defaults to
+// relevance when searching.
+func (app *application) readItemSort(qs url.Values, query data.ItemQuery, v *validator.Validator) string {
+	sort := app.readString(qs, "sort", "id")
+	if query.Search != "" && !qs.Has("sort") {
+		sort = "relevance"
+	}
+
+	v.Check(sort != "relevance" || query.Search != "", "sort", "relevance can only be used together with q")
+
+	return sort
+}
+
+func (app *application) readPriceBound(qs url.Values, key string, v *validator.Validator) *data.Money {
+	value := qs.Get(key)
+	if value == "" {
+		return nil
+	}
+
+	amount, err := data.ParseMoney(value)
+	if err != nil {
+		v.AddError(key, "must be a decimal amount")
+		return nil
+	}
+
+	v.Check(amount.Sign() >= 0, key, "must not be negative")
+
+	return &amount
+}
+
+func (app *application) handleListItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		v := validator.New()
 
-		if data.ValidateItem(v, item); !v.Valid() {
+		qs := r.URL.Query()
+
+		query := app.readItemQuery(qs, v)
+		sort := app.readItemSort(qs, query, v)
+
+		filters := data.Filters{
+			Page:         app.readInt(qs, "page", 1, v),
+			PageSize:     app.readInt(qs, "page_size", 20, v),
+			Sort:         sort,
+			SortSafelist: itemSortSafelistFor(sort),
+		}
+
+		// Passing cursor, even empty for the first page, switches to keyset
+		// pagination; next_cursor in the metadata leads to the page after.
+		if qs.Has("cursor") {
+			v.Check(!qs.Has("page"), "page", "must not be used together with cursor")
+
+			cursor, err := data.DecodeCursor(qs.Get("cursor"))
+			if err != nil {
+				v.AddError("cursor", "must be a next_cursor value from a previous page")
+			}
+			filters.Cursor = cursor
+		}
+
+		// Listings can only be expanded with stock, which is read for the
+		// whole page at once.
+		expand := app.readCSV(qs, "expand", []string{})
+		for _, field := range expand {
+			v.Check(field == "stock", "expand", "invalid expand value")
+		}
+
+		facetNames := app.readCSV(qs, "facets", []string{})
+		for _, name := range facetNames {
+			v.Check(validator.PermittedValue(name, data.ItemFacetNames...), "facets", "must only contain "+strings.Join(data.ItemFacetNames, ", "))
+		}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
 			app.failedValidationResponse(w, r, v.Errors)
 			return
 		}
 
-		err = app.models.Items.Update(item)
+		err := app.fallBackToFuzzy(qs, &query)
 		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrEditConflict):
-				app.editConflictResponse(w, r)
-			default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		items, metadata, err := app.models.Items.GetAll(query, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, item := range items {
+			app.setPriceDisplay(item)
+			app.setImageURLs(item)
+		}
+
+		if len(expand) > 0 {
+			err = app.setStockSummaries(items)
+			if err != nil {
 				app.serverErrorResponse(w, r, err)
+				return
 			}
-			return
 		}
 
-		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		env := envelope{"items": items, "metadata": metadata}
+
+		if len(facetNames) > 0 {
+			env["facets"], err = app.models.Items.Facets(query, facetNames)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		// Tell the client when the results came from a fuzzy search, so it
+		// can say so and ask for the following pages with fuzzy=true.
+		if query.Search != "" {
+			env["fuzzy"] = query.Fuzzy
+		}
+
+		err = app.writeJSON(w, http.StatusOK, env, nil)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 		}
 	}
 }
 
-func (app *application) handleDeleteItem() http.HandlerFunc {
+// setStockSummaries fills in the stock summary of each of the items.
+func (app *application) setStockSummaries(items []*data.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	summaries, err := app.models.Stock.Summaries(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Stock = summaries[item.ID]
+	}
+
+	return nil
+}
+
+func (app *application) handleListItemPrices() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := app.readIDParam(r)
-		if err != nil || id < 1 {
+		if err != nil {
 			app.notFoundResponse(w, r)
 			return
 		}
 
-		err = app.models.Items.Delete(id)
+		_, err = app.models.Items.Get(id)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrNoRecord):
@@ -205,46 +657,45 @@ func (app *application) handleDeleteItem() http.HandlerFunc {
 			return
 		}
 
-		err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully deleted"}, nil)
+		prices, err := app.models.ItemPrices.GetAllForItem(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"prices": prices}, nil)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 		}
 	}
 }
 
-func (app *application) handleListItems() http.HandlerFunc {
+func (app *application) handleSuggestItems() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var requestPayload struct {
-			Name     string
-			Supplier int
-			Tags     []string
-			data.Filters
-		}
-
 		v := validator.New()
 
 		qs := r.URL.Query()
 
-		requestPayload.Name = app.readString(qs, "name", "")
-		requestPayload.Supplier = app.readInt(qs, "supplier", 0, v)
-		requestPayload.Tags = app.readCSV(qs, "tags", []string{})
-		requestPayload.Filters.Page = app.readInt(qs, "page", 1, v)
-		requestPayload.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-		requestPayload.Filters.Sort = app.readString(qs, "sort", "id")
-		requestPayload.Filters.SortSafelist = []string{"id", "name", "model", "supplier", "price", "-id", "-name", "-model", "-price"}
+		prefix := strings.TrimSpace(qs.Get("q"))
+		limit := app.readInt(qs, "limit", 10, v)
+
+		v.Check(prefix != "", "q", "must be provided")
+		v.Check(len(prefix) <= 100, "q", "must not be more than 100 characters long")
+		v.Check(limit > 0, "limit", "must be a positive integer")
+		v.Check(limit <= 20, "limit", "must be a maximum of 20")
 
-		if data.ValidateFilters(v, requestPayload.Filters); !v.Valid() {
+		if !v.Valid() {
 			app.failedValidationResponse(w, r, v.Errors)
 			return
 		}
 
-		items, metadata, err := app.models.Items.GetAll(requestPayload.Name, requestPayload.Supplier, requestPayload.Tags, requestPayload.Filters)
+		suggestions, err := app.models.Items.Suggest(prefix, limit)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 
-		err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+		err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 		}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleListLocations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locations, err := app.models.Locations.GetTree()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"locations": locations}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleCreateLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Name   string `json:"name"`
+			Kind   string `json:"kind"`
+			Parent *int64 `json:"parent"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		location := &data.Location{
+			Name:   requestPayload.Name,
+			Kind:   requestPayload.Kind,
+			Parent: requestPayload.Parent,
+		}
+
+		v := validator.New()
+
+		if data.ValidateLocation(v, location); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Locations.Insert(location)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateLocationName):
+				v.AddError("name", "a location with this name already exists under the same parent")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownLocation):
+				v.AddError("parent", "must reference an existing location")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrLocationLargerParent):
+				v.AddError("kind", "must not be larger than the kind of the parent location")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/locations/%d", location.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"location": location}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		location, err := app.models.Locations.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"location": location}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleUpdateLocation renames a location, changes its kind or moves it under
+// another parent. A null parent makes it a top level location.
+func (app *application) handleUpdateLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		location, err := app.models.Locations.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Name   *string    `json:"name"`
+			Kind   *string    `json:"kind"`
+			Parent nullableID `json:"parent"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if requestPayload.Name != nil {
+			location.Name = *requestPayload.Name
+		}
+
+		if requestPayload.Kind != nil {
+			location.Kind = *requestPayload.Kind
+		}
+
+		if requestPayload.Parent.Set {
+			location.Parent = requestPayload.Parent.Value
+		}
+
+		v := validator.New()
+
+		if data.ValidateLocation(v, location); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Locations.Update(location)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateLocationName):
+				v.AddError("name", "a location with this name already exists under the same parent")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownLocation):
+				v.AddError("parent", "must reference an existing location")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrLocationCycle):
+				v.AddError("parent", "must not be a sublocation of the location being moved")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrLocationLargerParent):
+				v.AddError("kind", "must not be larger than the kind of the parent location")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrLocationSmallerChild):
+				v.AddError("kind", "must not be smaller than the kind of any sublocation")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"location": location}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleDeleteLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		err = app.models.Locations.Delete(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			case errors.Is(err, data.ErrLocationInUse):
+				app.recordInUseResponse(w, r, "the location still has sublocations or holds stock")
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -16,18 +16,66 @@ import (
 	"github.com/vmx-pso/item-service/internal/data"
 	"github.com/vmx-pso/item-service/internal/jsonlog"
 	"github.com/vmx-pso/item-service/internal/mailer"
+	"github.com/vmx-pso/item-service/internal/storage"
+	"github.com/vmx-pso/item-service/internal/validator"
 	"github.com/vmx-pso/item-service/internal/vcs"
 
 	_ "github.com/lib/pq"
 )
 
 type config struct {
-	port    int
-	env     string
-	db      db
-	limiter rateLimiter
-	smtp    smtp
-	cors    cors
+	port        int
+	env         string
+	db          db
+	limiter     rateLimiter
+	smtp        smtp
+	cors        cors
+	workers     workers
+	storage     storageConfig
+	images      imageConfig
+	attachments attachmentConfig
+	imports     importConfig
+	trash       trash
+	search      searchConfig
+}
+
+type searchConfig struct {
+	language string
+}
+
+type storageConfig struct {
+	backend string
+	dir     string
+	s3      s3
+}
+
+type s3 struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+}
+
+type imageConfig struct {
+	maxBytes int64
+}
+
+type attachmentConfig struct {
+	maxBytes int64
+}
+
+type importConfig struct {
+	maxBytes int64
+}
+
+type workers struct {
+	priceChangeInterval time.Duration
+	trashPurgeInterval  time.Duration
+}
+
+type trash struct {
+	retention time.Duration
 }
 
 type cors struct {
@@ -56,12 +104,13 @@ type smtp struct {
 }
 
 type application struct {
-	config config
-	router httprouter.Router
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config  config
+	router  httprouter.Router
+	logger  *jsonlog.Logger
+	models  data.Models
+	mailer  mailer.Mailer
+	storage storage.Store
+	wg      sync.WaitGroup
 }
 
 var (
@@ -93,6 +142,22 @@ func run(args []string, logger *jsonlog.Logger) error {
 		smtpUsername   = flags.String("smtp-username", "5bd3436757a4cf", "SMTP username")
 		smtpPassword   = flags.String("smtp-password", "68e7ccd9cc75a8", "SMTP password")
 		smtpSender     = flags.String("smtp-sender", "IMS <no-reply@fakemail.com>", "SMTP sender")
+		storageBackend = flags.String("storage", "fs", "File storage backend (fs|s3)")
+		storageDir     = flags.String("storage-dir", "uploads", "Directory files are stored in by the fs backend")
+		s3Endpoint     = flags.String("s3-endpoint", "", "S3 compatible endpoint URL")
+		s3Region       = flags.String("s3-region", "us-east-1", "S3 region")
+		s3Bucket       = flags.String("s3-bucket", "", "S3 bucket")
+		s3AccessKey    = flags.String("s3-access-key", "", "S3 access key")
+		s3SecretKey    = flags.String("s3-secret-key", "", "S3 secret key")
+		imageMaxBytes  = flags.Int64("image-max-bytes", 10<<20, "Maximum size of an uploaded item image in bytes")
+		attachMaxBytes = flags.Int64("attachment-max-bytes", 25<<20, "Maximum size of an uploaded item attachment in bytes")
+		importMaxBytes = flags.Int64("import-max-bytes", 10<<20, "Maximum size of an uploaded item import file in bytes")
+		priceInterval  = flags.Duration("price-change-interval", time.Minute, "How often scheduled price changes are applied")
+		trashRetention = flags.Duration("trash-retention", 30*24*time.Hour, "How long deleted items are kept in the trash before being purged")
+		trashInterval  = flags.Duration("trash-purge-interval", time.Hour, "How often expired items are purged from the trash")
+		tagsTrim       = flags.Bool("tags-trim", true, "Trim white space from item tags")
+		tagsFoldCase   = flags.Bool("tags-fold-case", false, "Lower case item tags")
+		searchLanguage = flags.String("search-language", "simple", "Default text search configuration for item searches ("+strings.Join(data.SearchLanguages, "|")+")")
 		displayVersion = flags.Bool("version", false, "Display version and exit")
 	)
 	flags.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
@@ -133,6 +198,50 @@ func run(args []string, logger *jsonlog.Logger) error {
 		cors: cors{
 			trustedOrigins: corsTrustedOrigins,
 		},
+		workers: workers{
+			priceChangeInterval: *priceInterval,
+			trashPurgeInterval:  *trashInterval,
+		},
+		trash: trash{
+			retention: *trashRetention,
+		},
+		storage: storageConfig{
+			backend: *storageBackend,
+			dir:     *storageDir,
+			s3: s3{
+				endpoint:  *s3Endpoint,
+				region:    *s3Region,
+				bucket:    *s3Bucket,
+				accessKey: *s3AccessKey,
+				secretKey: *s3SecretKey,
+			},
+		},
+		images: imageConfig{
+			maxBytes: *imageMaxBytes,
+		},
+		attachments: attachmentConfig{
+			maxBytes: *attachMaxBytes,
+		},
+		imports: importConfig{
+			maxBytes: *importMaxBytes,
+		},
+		search: searchConfig{
+			language: *searchLanguage,
+		},
+	}
+
+	if !validator.PermittedValue(cfg.search.language, data.SearchLanguages...) {
+		return fmt.Errorf("invalid -search-language %q", cfg.search.language)
+	}
+
+	data.TagRules = data.TagNormalization{
+		Trim:     *tagsTrim,
+		FoldCase: *tagsFoldCase,
+	}
+
+	store, err := openStorage(cfg.storage)
+	if err != nil {
+		return err
 	}
 
 	db, err := openDB(*dsn, *maxOpenConns, *maxIdleConns, *maxIdleTime)
@@ -156,12 +265,21 @@ func run(args []string, logger *jsonlog.Logger) error {
 		return time.Now().Unix()
 	}))
 
+	models := data.NewModels(db)
+
+	err = models.Currencies.Load()
+	if err != nil {
+		return err
+	}
+	logger.PrintInfo("currency registry loaded", nil)
+
 	app := &application{
-		config: cfg,
-		router: *httprouter.New(),
-		logger: logger,
-		models: *data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:  cfg,
+		router:  *httprouter.New(),
+		logger:  logger,
+		models:  *models,
+		mailer:  mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage: store,
 	}
 
 	return app.serve()
@@ -193,3 +311,14 @@ func openDB(dsn string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sq
 
 	return db, nil
 }
+
+func openStorage(cfg storageConfig) (storage.Store, error) {
+	switch cfg.backend {
+	case "fs":
+		return storage.NewFS(cfg.dir), nil
+	case "s3":
+		return storage.NewS3(cfg.s3.endpoint, cfg.s3.region, cfg.s3.bucket, cfg.s3.accessKey, cfg.s3.secretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.backend)
+	}
+}
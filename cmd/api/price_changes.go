@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleCreateItemPriceChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		item, err := app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Price       data.Price `json:"price"`
+			Currency    *string    `json:"currency"`
+			EffectiveAt time.Time  `json:"effectiveAt"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := app.contextGetUser(r)
+
+		change := &data.ItemPriceChange{
+			ItemID:      item.ID,
+			Price:       requestPayload.Price,
+			Currency:    item.Currency,
+			EffectiveAt: requestPayload.EffectiveAt,
+			CreatedBy:   &user.ID,
+		}
+
+		if requestPayload.Currency != nil {
+			change.Currency = *requestPayload.Currency
+		}
+
+		v := validator.New()
+
+		if data.ValidateItemPriceChange(v, change, app.models.Currencies.Registry); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.ItemPriceChanges.Insert(change)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/items/%d/price-changes", item.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"price_change": change}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleListItemPriceChanges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		status := app.readString(r.URL.Query(), "status", data.PriceChangePending)
+		v.Check(validator.PermittedValue(status, data.PriceChangePending, data.PriceChangeApplied, data.PriceChangeCancelled, data.PriceChangeFailed, "all"), "status", "invalid status value")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if status == "all" {
+			status = ""
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		changes, err := app.models.ItemPriceChanges.GetAllForItem(id, status)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"price_changes": changes}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleCancelItemPriceChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		changeID, err := app.readInt64Param(r, "change")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.models.ItemPriceChanges.Cancel(id, changeID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "price change successfully cancelled"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// applyScheduledPriceChanges periodically applies pending price changes that
+// have become effective until ctx is cancelled on shutdown.
+func (app *application) applyScheduledPriceChanges(ctx context.Context) {
+	ticker := time.NewTicker(app.config.workers.priceChangeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			applied, err := app.models.ItemPriceChanges.ApplyDue(time.Now())
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+
+			if applied > 0 {
+				app.logger.PrintInfo("applied scheduled price changes", map[string]string{
+					"count": strconv.Itoa(applied),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleListItemRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		filters := data.Filters{
+			Page:         app.readInt(qs, "page", 1, v),
+			PageSize:     app.readInt(qs, "page_size", 20, v),
+			Sort:         "-revision",
+			SortSafelist: []string{"-revision"},
+		}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		revisions, metadata, err := app.models.ItemRevisions.GetAllForItem(id, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// Deleted items keep their revisions, so only report a missing item
+		// when it has no history at all.
+		if len(revisions) == 0 {
+			_, err = app.models.Items.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrNoRecord):
+					app.notFoundResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowItemRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		rev, err := app.readInt64Param(r, "rev")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		revision, err := app.models.ItemRevisions.Get(id, rev)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"revision": revision}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleDiffItemRevisions compares revision :rev with the revision given by
+// ?to=, or with the latest revision if it is omitted.
+func (app *application) handleDiffItemRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		rev, err := app.readInt64Param(r, "rev")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		to := app.readInt(r.URL.Query(), "to", 0, v)
+		v.Check(to >= 0, "to", "must be a positive integer")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		from, err := app.models.ItemRevisions.Get(id, rev)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var target *data.ItemRevision
+		if to == 0 {
+			target, err = app.models.ItemRevisions.GetLatest(id)
+		} else {
+			target, err = app.models.ItemRevisions.Get(id, int64(to))
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				v.AddError("to", "must be an existing revision of the item")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		changes, err := data.DiffItemRevisions(from, target)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"from": from.Revision, "to": target.Revision, "changes": changes}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// handleRestoreItemRevision sets an item's fields back to those of an earlier
+// revision. The item's archived state is left as it is.
+func (app *application) handleRestoreItemRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		rev, err := app.readInt64Param(r, "rev")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		item, err := app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		revision, err := app.models.ItemRevisions.Get(id, rev)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		previous, err := revision.Item()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		item.Name = previous.Name
+		item.Model = previous.Model
+		item.Supplier = previous.Supplier
+		item.Category = previous.Category
+		item.Price = previous.Price
+		item.Currency = previous.Currency
+		item.ImageFile = previous.ImageFile
+		item.Notes = previous.Notes
+		item.Tags = previous.Tags
+		item.Attributes = previous.Attributes
+
+		schema, err := app.models.Attributes.Schema(item.Category)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateItem(v, item, app.models.Currencies.Registry, schema); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Items.Restore(item, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrUnknownSupplier):
+				v.AddError("supplier", "must reference an existing supplier")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrUnknownCategory):
+				v.AddError("category", "must reference an existing category")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -17,9 +17,69 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/items", app.requirePermission("items:read", app.handleListItems()))
 	router.HandlerFunc(http.MethodPost, "/v1/items", app.requirePermission("items:write", app.handleCreateItem()))
-	router.HandlerFunc(http.MethodGet, "/v1/items/:id", app.requirePermission("items:read", app.handleShowItem()))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id", app.itemSubroutes(app.notFoundResponse, map[string]http.HandlerFunc{
+		"batch":  app.requirePermission("items:write", app.handleBatchItems()),
+		"import": app.requirePermission("items:write", app.handleImportItems()),
+	}))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id", app.itemSubroutes(app.requirePermission("items:read", app.handleShowItem()), map[string]http.HandlerFunc{
+		"export":  app.requirePermission("items:read", app.handleExportItems()),
+		"suggest": app.requirePermission("items:read", app.handleSuggestItems()),
+	}))
 	router.HandlerFunc(http.MethodPatch, "/v1/items/:id", app.requirePermission("items:write", app.handleUpdateItem()))
 	router.HandlerFunc(http.MethodDelete, "/v1/items/:id", app.requirePermission("items:write", app.handleDeleteItem()))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id/archive", app.requirePermission("items:write", app.handleArchiveItem(true)))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id/unarchive", app.requirePermission("items:write", app.handleArchiveItem(false)))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/image", app.requirePermission("items:read", app.handleShowItemImage()))
+	router.HandlerFunc(http.MethodPut, "/v1/items/:id/image", app.requirePermission("items:write", app.handleUploadItemImage()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/attachments", app.requirePermission("items:read", app.handleListItemAttachments()))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id/attachments", app.requirePermission("items:write", app.handleCreateItemAttachment()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/attachments/:attachment", app.requirePermission("items:read", app.handleShowItemAttachment()))
+	router.HandlerFunc(http.MethodDelete, "/v1/items/:id/attachments/:attachment", app.requirePermission("items:write", app.handleDeleteItemAttachment()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/revisions", app.requirePermission("items:read", app.handleListItemRevisions()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/revisions/:rev", app.requirePermission("items:read", app.handleShowItemRevision()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/revisions/:rev/diff", app.requirePermission("items:read", app.handleDiffItemRevisions()))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id/revisions/:rev/restore", app.requirePermission("items:write", app.handleRestoreItemRevision()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/prices", app.requirePermission("items:read", app.handleListItemPrices()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/price-changes", app.requirePermission("items:read", app.handleListItemPriceChanges()))
+	router.HandlerFunc(http.MethodPost, "/v1/items/:id/price-changes", app.requirePermission("items:write", app.handleCreateItemPriceChange()))
+	router.HandlerFunc(http.MethodDelete, "/v1/items/:id/price-changes/:change", app.requirePermission("items:write", app.handleCancelItemPriceChange()))
+	router.HandlerFunc(http.MethodGet, "/v1/items/:id/stock", app.requirePermission("items:read", app.handleShowItemStock()))
+	router.HandlerFunc(http.MethodPut, "/v1/items/:id/stock/:location", app.requirePermission("stock:write", app.handleSetItemStock()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/trash", app.requirePermission("items:read", app.handleListTrash()))
+	router.HandlerFunc(http.MethodPost, "/v1/trash/:id/restore", app.requirePermission("items:write", app.handleRestoreTrashedItem()))
+	router.HandlerFunc(http.MethodDelete, "/v1/trash/:id", app.requirePermission("items:purge", app.handlePurgeItem()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/categories", app.requirePermission("items:read", app.handleListCategories()))
+	router.HandlerFunc(http.MethodPost, "/v1/categories", app.requirePermission("items:write", app.handleCreateCategory()))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.requirePermission("items:read", app.handleShowCategory()))
+	router.HandlerFunc(http.MethodPatch, "/v1/categories/:id", app.requirePermission("items:write", app.handleUpdateCategory()))
+	router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", app.requirePermission("items:write", app.handleDeleteCategory()))
+	router.HandlerFunc(http.MethodPost, "/v1/categories/:id/move", app.requirePermission("items:write", app.handleMoveCategory()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/attributes", app.requirePermission("items:read", app.handleListAttributes()))
+	router.HandlerFunc(http.MethodPost, "/v1/attributes", app.requirePermission("items:write", app.handleCreateAttribute()))
+	router.HandlerFunc(http.MethodGet, "/v1/attributes/:id", app.requirePermission("items:read", app.handleShowAttribute()))
+	router.HandlerFunc(http.MethodPatch, "/v1/attributes/:id", app.requirePermission("items:write", app.handleUpdateAttribute()))
+	router.HandlerFunc(http.MethodDelete, "/v1/attributes/:id", app.requirePermission("items:write", app.handleDeleteAttribute()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/locations", app.requirePermission("items:read", app.handleListLocations()))
+	router.HandlerFunc(http.MethodPost, "/v1/locations", app.requirePermission("stock:write", app.handleCreateLocation()))
+	router.HandlerFunc(http.MethodGet, "/v1/locations/:id", app.requirePermission("items:read", app.handleShowLocation()))
+	router.HandlerFunc(http.MethodPatch, "/v1/locations/:id", app.requirePermission("stock:write", app.handleUpdateLocation()))
+	router.HandlerFunc(http.MethodDelete, "/v1/locations/:id", app.requirePermission("stock:write", app.handleDeleteLocation()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requirePermission("items:read", app.handleListTags()))
+	router.HandlerFunc(http.MethodPost, "/v1/tags/rename", app.requirePermission("items:write", app.handleRenameTag()))
+	router.HandlerFunc(http.MethodPost, "/v1/tags/merge", app.requirePermission("items:write", app.handleMergeTags()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/currencies", app.requirePermission("items:read", app.handleListCurrencies()))
+
+	router.HandlerFunc(http.MethodGet, "/v1/suppliers", app.requirePermission("suppliers:read", app.handleListSuppliers()))
+	router.HandlerFunc(http.MethodPost, "/v1/suppliers", app.requirePermission("suppliers:write", app.handleCreateSupplier()))
+	router.HandlerFunc(http.MethodGet, "/v1/suppliers/:id", app.requirePermission("suppliers:read", app.handleShowSupplier()))
+	router.HandlerFunc(http.MethodPatch, "/v1/suppliers/:id", app.requirePermission("suppliers:write", app.handleUpdateSupplier()))
+	router.HandlerFunc(http.MethodDelete, "/v1/suppliers/:id", app.requirePermission("suppliers:write", app.handleDeleteSupplier()))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.handleRegisterUser())
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.handleActivateUser())
@@ -30,3 +90,19 @@ func (app *application) routes() http.Handler {
 
 	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
 }
+
+// itemSubroutes serves fixed paths such as /v1/items/batch, which httprouter
+// can't register next to /v1/items/:id. Requests whose :id matches one of the
+// subroutes go to its handler and all others go to next.
+func (app *application) itemSubroutes(next http.HandlerFunc, subroutes map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		if handler, ok := subroutes[params.ByName("id")]; ok {
+			handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -22,6 +22,17 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	app.background(func() {
+		app.applyScheduledPriceChanges(ctx)
+	})
+
+	app.background(func() {
+		app.purgeExpiredTrash(ctx)
+	})
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -43,6 +54,7 @@ func (app *application) serve() error {
 			"addr": srv.Addr,
 		})
 
+		stopWorkers()
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
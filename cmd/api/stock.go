@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+// handleShowItemStock lists how many of an item are on hand at each location,
+// along with the total.
+func (app *application) handleShowItemStock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.writeItemStock(w, r, id)
+	}
+}
+
+// handleSetItemStock records a count of an item at a location and responds
+// with the item's stock everywhere.
+func (app *application) handleSetItemStock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		locationID, err := app.readInt64Param(r, "location")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		_, err = app.models.Items.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Quantity *int `json:"quantity"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		v.Check(requestPayload.Quantity != nil, "quantity", "must be provided")
+
+		if requestPayload.Quantity != nil {
+			data.ValidateStockQuantity(v, *requestPayload.Quantity)
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Stock.Set(id, locationID, *requestPayload.Quantity, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrUnknownLocation), errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.writeItemStock(w, r, id)
+	}
+}
+
+func (app *application) writeItemStock(w http.ResponseWriter, r *http.Request, itemID int64) {
+	levels, err := app.models.Stock.GetForItem(itemID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	total := 0
+	for _, level := range levels {
+		total += level.Quantity
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stock": levels, "total": total}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
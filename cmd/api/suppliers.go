@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleCreateSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Name          string `json:"name"`
+			ContactName   string `json:"contactName"`
+			Email         string `json:"email"`
+			Phone         string `json:"phone"`
+			AccountNumber string `json:"accountNumber"`
+			Notes         string `json:"notes"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		supplier := &data.Supplier{
+			Name:          requestPayload.Name,
+			ContactName:   requestPayload.ContactName,
+			Email:         requestPayload.Email,
+			Phone:         requestPayload.Phone,
+			AccountNumber: requestPayload.AccountNumber,
+			Notes:         requestPayload.Notes,
+		}
+
+		v := validator.New()
+
+		if data.ValidateSupplier(v, supplier); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Suppliers.Insert(supplier)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateSupplierName):
+				v.AddError("name", "a supplier with this name already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/suppliers/%d", supplier.ID))
+
+		err = app.writeJSON(w, http.StatusCreated, envelope{"supplier": supplier}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleShowSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		supplier, err := app.models.Suppliers.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"supplier": supplier}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleUpdateSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		supplier, err := app.models.Suppliers.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var requestPayload struct {
+			Name          *string `json:"name"`
+			ContactName   *string `json:"contactName"`
+			Email         *string `json:"email"`
+			Phone         *string `json:"phone"`
+			AccountNumber *string `json:"accountNumber"`
+			Notes         *string `json:"notes"`
+		}
+
+		err = app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if requestPayload.Name != nil {
+			supplier.Name = *requestPayload.Name
+		}
+
+		if requestPayload.ContactName != nil {
+			supplier.ContactName = *requestPayload.ContactName
+		}
+
+		if requestPayload.Email != nil {
+			supplier.Email = *requestPayload.Email
+		}
+
+		if requestPayload.Phone != nil {
+			supplier.Phone = *requestPayload.Phone
+		}
+
+		if requestPayload.AccountNumber != nil {
+			supplier.AccountNumber = *requestPayload.AccountNumber
+		}
+
+		if requestPayload.Notes != nil {
+			supplier.Notes = *requestPayload.Notes
+		}
+
+		v := validator.New()
+
+		if data.ValidateSupplier(v, supplier); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.models.Suppliers.Update(supplier)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateSupplierName):
+				v.AddError("name", "a supplier with this name already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"supplier": supplier}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleDeleteSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		err = app.models.Suppliers.Delete(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			case errors.Is(err, data.ErrSupplierInUse):
+				app.recordInUseResponse(w, r, "the supplier is still referenced by one or more items")
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleListSuppliers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Name string
+			data.Filters
+		}
+
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		requestPayload.Name = app.readString(qs, "name", "")
+		requestPayload.Filters.Page = app.readInt(qs, "page", 1, v)
+		requestPayload.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+		requestPayload.Filters.Sort = app.readString(qs, "sort", "id")
+		requestPayload.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+		if data.ValidateFilters(v, requestPayload.Filters); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		suppliers, metadata, err := app.models.Suppliers.GetAll(requestPayload.Name, requestPayload.Filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"suppliers": suppliers, "metadata": metadata}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleListTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		prefix := strings.TrimSpace(qs.Get("prefix"))
+
+		filters := data.Filters{
+			Page:         app.readInt(qs, "page", 1, v),
+			PageSize:     app.readInt(qs, "page_size", 50, v),
+			Sort:         app.readString(qs, "sort", "-count"),
+			SortSafelist: []string{"name", "count", "-name", "-count"},
+		}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		tags, metadata, err := app.models.Tags.GetAll(prefix, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"tags": tags, "metadata": metadata}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleRenameTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		to := data.NormalizeTag(requestPayload.To)
+
+		v := validator.New()
+
+		v.Check(requestPayload.From != "", "from", "must be provided")
+		data.ValidateTag(v, "to", to)
+		v.Check(to != requestPayload.From, "to", "must be different from from")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		app.mergeTags(w, r, []string{requestPayload.From}, to)
+	}
+}
+
+func (app *application) handleMergeTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload struct {
+			Sources []string `json:"sources"`
+			Target  string   `json:"target"`
+		}
+
+		err := app.readJSON(w, r, &requestPayload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		target := data.NormalizeTag(requestPayload.Target)
+
+		v := validator.New()
+
+		v.Check(len(requestPayload.Sources) > 0, "sources", "must contain at least one tag")
+		v.Check(len(requestPayload.Sources) <= 50, "sources", "must not contain more than 50 tags")
+		v.Check(validator.Unique(requestPayload.Sources), "sources", "must not contain duplicate values")
+		for _, source := range requestPayload.Sources {
+			v.Check(source != "", "sources", "must not contain empty tags")
+		}
+		data.ValidateTag(v, "target", target)
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		// Items already carrying only the target need no change.
+		var sources []string
+		for _, source := range requestPayload.Sources {
+			if source != target {
+				sources = append(sources, source)
+			}
+		}
+
+		if len(sources) == 0 {
+			v.AddError("sources", "must contain a tag other than the target")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		app.mergeTags(w, r, sources, target)
+	}
+}
+
+// mergeTags rewrites sources to target across all items and reports how many
+// items changed.
+func (app *application) mergeTags(w http.ResponseWriter, r *http.Request, sources []string, target string) {
+	updated, err := app.models.Tags.Merge(sources, target, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tag": target, "updated_items": updated}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/data"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func (app *application) handleListTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		filters := data.Filters{
+			Page:         app.readInt(qs, "page", 1, v),
+			PageSize:     app.readInt(qs, "page_size", 20, v),
+			Sort:         "-deleted_at",
+			SortSafelist: []string{"-deleted_at"},
+		}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		items, metadata, err := app.models.Items.GetTrash(filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handleRestoreTrashedItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		item, err := app.models.Items.Undelete(id, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.setPriceDisplay(item)
+		app.setImageURLs(item)
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (app *application) handlePurgeItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		keys, err := app.models.Items.Purge(id, app.contextGetUser(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrNoRecord):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.background(func() {
+			app.deleteStoredFiles(keys...)
+		})
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "item permanently deleted"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// purgeExpiredTrash periodically purges items that have been in the trash for
+// longer than the retention window until ctx is cancelled on shutdown.
+func (app *application) purgeExpiredTrash(ctx context.Context) {
+	ticker := time.NewTicker(app.config.workers.trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, keys, err := app.models.Items.PurgeExpired(time.Now().Add(-app.config.trash.retention))
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+
+			app.deleteStoredFiles(keys...)
+
+			if purged > 0 {
+				app.logger.PrintInfo("purged expired items from trash", map[string]string{
+					"count": strconv.Itoa(purged),
+				})
+			}
+		}
+	}
+}
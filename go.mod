@@ -8,6 +8,7 @@ require (
 	github.com/lib/pq v1.10.7
 	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
 	golang.org/x/crypto v0.1.0
+	golang.org/x/image v0.14.0
 	golang.org/x/time v0.1.0
 )
 
@@ -0,0 +1,471 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+var ErrDuplicateAttributeName = errors.New("duplicate attribute name")
+
+// AttributeTypes are the types an attribute value can have. Dates are
+// strings in YYYY-MM-DD form and enums are strings from the definition's
+// EnumValues.
+var AttributeTypes = []string{"text", "number", "integer", "boolean", "date", "enum"}
+
+// AttributeNameRX is the form of attribute names. They appear in filter
+// expressions and sort values, so they are kept to plain identifiers.
+var AttributeNameRX = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+const maxAttributeTextLength = 1000
+
+// itemAttributeSortPrefix starts item sort values that order by a custom
+// attribute, such as attributes.length.
+const itemAttributeSortPrefix = "attributes."
+
+// IsAttributeSort reports whether sort orders items by a custom attribute,
+// either ascending or descending. Such sorts can't be listed in a safelist
+// up front, so callers add them once this accepts them.
+func IsAttributeSort(sort string) bool {
+	sort = strings.TrimPrefix(sort, "-")
+	return strings.HasPrefix(sort, itemAttributeSortPrefix) && AttributeNameRX.MatchString(strings.TrimPrefix(sort, itemAttributeSortPrefix))
+}
+
+// AttributeDefinition describes a custom attribute items can carry. Global
+// definitions have no Category and apply to every item; the others apply to
+// items in their category and its subcategories, where a definition of the
+// same name in a nearer category takes precedence.
+type AttributeDefinition struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Unit       string    `json:"unit,omitempty"`
+	Required   bool      `json:"required"`
+	EnumValues []string  `json:"enumValues,omitempty"`
+	Min        *float64  `json:"min,omitempty"`
+	Max        *float64  `json:"max,omitempty"`
+	Category   *int64    `json:"category"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Version    int       `json:"version"`
+}
+
+func ValidateAttributeDefinition(v *validator.Validator, def *AttributeDefinition) {
+	v.Check(def.Name != "", "name", "must be provided")
+	v.Check(validator.Matches(def.Name, AttributeNameRX), "name", "must start with a lower case letter and contain only lower case letters, digits and underscores, up to 50 characters")
+	v.Check(validator.PermittedValue(def.Type, AttributeTypes...), "type", "must be one of "+strings.Join(AttributeTypes, ", "))
+	v.Check(len(def.Unit) <= 20, "unit", "must not be more than 20 characters long")
+
+	if def.Type == "enum" {
+		v.Check(len(def.EnumValues) > 0, "enumValues", "must be provided for enum attributes")
+		v.Check(len(def.EnumValues) <= 100, "enumValues", "must not contain more than 100 values")
+		for _, value := range def.EnumValues {
+			v.Check(value != "", "enumValues", "must not contain empty values")
+			v.Check(len(value) <= 100, "enumValues", "must not contain values more than 100 characters long")
+		}
+		v.Check(validator.Unique(def.EnumValues), "enumValues", "must not contain duplicate values")
+	} else {
+		v.Check(len(def.EnumValues) == 0, "enumValues", "must only be provided for enum attributes")
+	}
+
+	if def.Type != "number" && def.Type != "integer" {
+		v.Check(def.Min == nil, "min", "must only be provided for number and integer attributes")
+		v.Check(def.Max == nil, "max", "must only be provided for number and integer attributes")
+	}
+
+	if def.Min != nil && def.Max != nil {
+		v.Check(*def.Min <= *def.Max, "max", "must not be less than min")
+	}
+}
+
+// AttributeSchema holds the definitions that apply to an item, by name.
+type AttributeSchema map[string]*AttributeDefinition
+
+// ItemAttributes are the custom attribute values of an item. They are stored
+// as a JSON object; numbers decode as float64.
+type ItemAttributes map[string]any
+
+func (a ItemAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *ItemAttributes) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("can't scan %T into item attributes", src)
+	}
+	return json.Unmarshal(b, a)
+}
+
+// storedAttributes are the category and attribute values an item has in the
+// database.
+type storedAttributes struct {
+	category *int64
+	values   ItemAttributes
+}
+
+// markStored records the item's current category and attributes as the ones
+// it has in the database.
+func (item *Item) markStored() {
+	values := make(ItemAttributes, len(item.Attributes))
+	for name, value := range item.Attributes {
+		values[name] = value
+	}
+
+	item.stored = &storedAttributes{category: item.Category, values: values}
+}
+
+// unchanged reports whether attribute name already had value, and whether it
+// was already missing when value is nil, while the item stayed in the same
+// category. Such values were accepted by the definitions in force when they
+// were written and are not checked again.
+func (s *storedAttributes) unchanged(category *int64, name string, value any) bool {
+	if s == nil {
+		return false
+	}
+
+	sameCategory := s.category == nil && category == nil ||
+		s.category != nil && category != nil && *s.category == *category
+	if !sameCategory {
+		return false
+	}
+
+	previous, ok := s.values[name]
+	if value == nil {
+		return !ok
+	}
+	return ok && reflect.DeepEqual(previous, value)
+}
+
+// validateAttributes checks attributes against schema, adding errors keyed by
+// "attributes.<name>". Null values are removed first, so setting an attribute
+// to null clears it. Values stored already holds for the item's category are
+// skipped, as are required attributes it was already missing; stored is nil
+// for new items.
+func validateAttributes(v *validator.Validator, attributes ItemAttributes, category *int64, schema AttributeSchema, stored *storedAttributes) {
+	for name, value := range attributes {
+		if value == nil {
+			delete(attributes, name)
+		}
+	}
+
+	v.Check(len(attributes) <= 100, "attributes", "must not contain more than 100 attributes")
+
+	for name, value := range attributes {
+		if stored.unchanged(category, name, value) {
+			continue
+		}
+
+		def, ok := schema[name]
+		if !ok {
+			v.AddError("attributes."+name, "is not defined for the item's category")
+			continue
+		}
+
+		if msg := checkAttributeValue(def, value); msg != "" {
+			v.AddError("attributes."+name, msg)
+		}
+	}
+
+	for name, def := range schema {
+		if _, ok := attributes[name]; def.Required && !ok && !stored.unchanged(category, name, nil) {
+			v.AddError("attributes."+name, "must be provided")
+		}
+	}
+}
+
+// checkAttributeValue returns why value isn't valid for def, or an empty
+// string if it is.
+func checkAttributeValue(def *AttributeDefinition, value any) string {
+	switch def.Type {
+	case "text":
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(s) > maxAttributeTextLength {
+			return fmt.Sprintf("must not be more than %d characters long", maxAttributeTextLength)
+		}
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if def.Type == "integer" && n != math.Trunc(n) {
+			return "must be an integer"
+		}
+		if def.Min != nil && n < *def.Min {
+			return "must be at least " + strconv.FormatFloat(*def.Min, 'f', -1, 64)
+		}
+		if def.Max != nil && n > *def.Max {
+			return "must be at most " + strconv.FormatFloat(*def.Max, 'f', -1, 64)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	case "date":
+		s, ok := value.(string)
+		if !ok {
+			return "must be a date (YYYY-MM-DD)"
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case "enum":
+		s, ok := value.(string)
+		if !ok || !validator.PermittedValue(s, def.EnumValues...) {
+			return "must be one of " + strings.Join(def.EnumValues, ", ")
+		}
+	}
+
+	return ""
+}
+
+type AttributeModel struct {
+	DB *sql.DB
+}
+
+const attributeDefinitionColumns = `d.id, d.name, d.type, d.unit, d.required, d.enum_values, d.min, d.max, d.category, d.created_at, d.version`
+
+func scanAttributeDefinition(row interface{ Scan(...any) error }) (*AttributeDefinition, error) {
+	var def AttributeDefinition
+
+	err := row.Scan(
+		&def.ID,
+		&def.Name,
+		&def.Type,
+		&def.Unit,
+		&def.Required,
+		pq.Array(&def.EnumValues),
+		&def.Min,
+		&def.Max,
+		&def.Category,
+		&def.CreatedAt,
+		&def.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &def, nil
+}
+
+func attributeDefinitionError(err error) error {
+	switch {
+	case err.Error() == `pq: duplicate key value violates unique constraint "attribute_definitions_category_name_key"`:
+		return ErrDuplicateAttributeName
+	case err.Error() == `pq: insert or update on table "attribute_definitions" violates foreign key constraint "attribute_definitions_category_fkey"`:
+		return ErrUnknownCategory
+	default:
+		return err
+	}
+}
+
+func (m *AttributeModel) Insert(def *AttributeDefinition) error {
+	qry := `
+		INSERT INTO attribute_definitions (name, type, unit, required, enum_values, min, max, category)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, version`
+
+	args := []any{def.Name, def.Type, def.Unit, def.Required, pq.Array(def.EnumValues), def.Min, def.Max, def.Category}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&def.ID, &def.CreatedAt, &def.Version)
+	if err != nil {
+		return attributeDefinitionError(err)
+	}
+	return nil
+}
+
+func (m *AttributeModel) Get(id int64) (*AttributeDefinition, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
+	}
+
+	qry := `
+		SELECT ` + attributeDefinitionColumns + `
+		FROM attribute_definitions AS d
+		WHERE d.id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	def, err := scanAttributeDefinition(m.DB.QueryRowContext(ctx, qry, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return def, nil
+}
+
+// GetAll returns every definition, the global ones first, then by category
+// and name.
+func (m *AttributeModel) GetAll() ([]*AttributeDefinition, error) {
+	qry := `
+		SELECT ` + attributeDefinitionColumns + `
+		FROM attribute_definitions AS d
+		ORDER BY d.category ASC NULLS FIRST, d.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return queryAttributeDefinitions(ctx, m.DB, qry)
+}
+
+// ForCategory returns the definitions that apply to items in category, or to
+// items without a category if it is nil, ordered by name.
+func (m *AttributeModel) ForCategory(category *int64) ([]*AttributeDefinition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return attributeDefinitionsFor(ctx, m.DB, category)
+}
+
+// Schema is ForCategory keyed by name, for validating items.
+func (m *AttributeModel) Schema(category *int64) (AttributeSchema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return attributeSchema(ctx, m.DB, category)
+}
+
+func attributeSchema(ctx context.Context, q queryer, category *int64) (AttributeSchema, error) {
+	defs, err := attributeDefinitionsFor(ctx, q, category)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make(AttributeSchema, len(defs))
+	for _, def := range defs {
+		schema[def.Name] = def
+	}
+
+	return schema, nil
+}
+
+// attributeDefinitionsFor walks up from category to the top of the tree and
+// keeps, for each name, the definition from the nearest category, falling
+// back to the global one.
+func attributeDefinitionsFor(ctx context.Context, q queryer, category *int64) ([]*AttributeDefinition, error) {
+	qry := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT categories.id, categories.parent_id, ancestors.depth + 1
+			FROM categories
+			JOIN ancestors ON categories.id = ancestors.parent_id
+		)
+		SELECT DISTINCT ON (d.name) ` + attributeDefinitionColumns + `
+		FROM attribute_definitions AS d
+		LEFT JOIN ancestors ON ancestors.id = d.category
+		WHERE d.category IS NULL OR ancestors.id IS NOT NULL
+		ORDER BY d.name ASC, ancestors.depth ASC NULLS LAST`
+
+	return queryAttributeDefinitions(ctx, q, qry, category)
+}
+
+func queryAttributeDefinitions(ctx context.Context, q queryer, qry string, args ...any) ([]*AttributeDefinition, error) {
+	rows, err := q.QueryContext(ctx, qry, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []*AttributeDefinition{}
+
+	for rows.Next() {
+		def, err := scanAttributeDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+// Update changes how an attribute is constrained. Its name, type and category
+// are fixed once created, as stored values depend on them. The new
+// constraints, including Required, apply to new items and to values a write
+// changes; values items already carry are left alone.
+func (m *AttributeModel) Update(def *AttributeDefinition) error {
+	qry := `
+		UPDATE attribute_definitions
+		SET unit = $1, required = $2, enum_values = $3, min = $4, max = $5, version = version + 1
+		WHERE id = $6 AND version = $7
+		RETURNING version`
+
+	args := []any{def.Unit, def.Required, pq.Array(def.EnumValues), def.Min, def.Max, def.ID, def.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&def.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return attributeDefinitionError(err)
+		}
+	}
+	return nil
+}
+
+// Delete removes a definition. Values items already carry are kept and don't
+// stop those items being written, but they can't be changed or set again
+// unless another definition of the same name applies.
+func (m *AttributeModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrNoRecord
+	}
+
+	qry := `
+		DELETE FROM attribute_definitions
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func TestValidateItemStoredAttributes(t *testing.T) {
+	tools, garden := int64(1), int64(2)
+	max := 100.0
+
+	// The schema after "colour" was deleted, "length" tightened and
+	// "voltage" made required.
+	schema := AttributeSchema{
+		"length":  {Name: "length", Type: "number", Max: &max},
+		"voltage": {Name: "voltage", Type: "integer", Required: true},
+	}
+
+	tests := []struct {
+		name       string
+		category   *int64
+		attributes ItemAttributes
+		errors     []string
+	}{
+		{name: "untouched", category: &tools, attributes: ItemAttributes{"colour": "red", "length": 250.0}},
+		{name: "deleted definition changed", category: &tools, attributes: ItemAttributes{"colour": "blue", "length": 250.0}, errors: []string{"attributes.colour"}},
+		{name: "tightened value changed", category: &tools, attributes: ItemAttributes{"colour": "red", "length": 300.0}, errors: []string{"attributes.length"}},
+		{name: "deleted attribute cleared", category: &tools, attributes: ItemAttributes{"colour": nil, "length": 250.0}},
+		{name: "required set", category: &tools, attributes: ItemAttributes{"voltage": 18.0}},
+		{name: "required invalid", category: &tools, attributes: ItemAttributes{"voltage": 1.5}, errors: []string{"attributes.voltage"}},
+		{name: "category changed", category: &garden, attributes: ItemAttributes{"colour": "red", "length": 250.0}, errors: []string{"attributes.colour", "attributes.length", "attributes.voltage"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &Item{Category: &tools, Attributes: ItemAttributes{"colour": "red", "length": 250.0}}
+			item.markStored()
+
+			item.Category = tt.category
+			item.Attributes = tt.attributes
+
+			v := validator.New()
+			validateAttributes(v, item.Attributes, item.Category, schema, item.stored)
+
+			if len(v.Errors) != len(tt.errors) {
+				t.Fatalf("got errors %v; want errors for %v", v.Errors, tt.errors)
+			}
+			for _, key := range tt.errors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("got errors %v; want one for %s", v.Errors, key)
+				}
+			}
+		})
+	}
+
+	t.Run("new item", func(t *testing.T) {
+		v := validator.New()
+		validateAttributes(v, ItemAttributes{"colour": "red"}, &tools, schema, nil)
+
+		for _, key := range []string{"attributes.colour", "attributes.voltage"} {
+			if _, ok := v.Errors[key]; !ok {
+				t.Errorf("got errors %v; want one for %s", v.Errors, key)
+			}
+		}
+	})
+}
@@ -0,0 +1,410 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+var (
+	ErrDuplicateCategoryName = errors.New("duplicate category name")
+	ErrUnknownParent         = errors.New("unknown parent category")
+	ErrCategoryCycle         = errors.New("category can't be moved into its own subtree")
+	ErrCategoryInUse         = errors.New("category has subcategories, attribute definitions or items")
+)
+
+// Category is a node of the category tree. Parent is nil for top level
+// categories, and siblings are ordered by Position.
+type Category struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Parent    *int64      `json:"parent"`
+	Position  int         `json:"position"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Version   int         `json:"version"`
+	Children  []*Category `json:"children,omitempty"`
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 characters long")
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func categoryError(err error) error {
+	switch {
+	case err.Error() == `pq: duplicate key value violates unique constraint "categories_parent_name_key"`:
+		return ErrDuplicateCategoryName
+	case err.Error() == `pq: insert or update on table "categories" violates foreign key constraint "categories_parent_id_fkey"`:
+		return ErrUnknownParent
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNoRecord
+	default:
+		return err
+	}
+}
+
+// Insert adds a category as the last child of its parent.
+func (m *CategoryModel) Insert(category *Category) error {
+	qry := `
+		INSERT INTO categories (name, parent_id, position)
+		VALUES ($1, $2, (SELECT COALESCE(max(position) + 1, 0) FROM categories WHERE parent_id IS NOT DISTINCT FROM $2))
+		RETURNING id, position, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, category.Name, category.Parent).Scan(&category.ID, &category.Position, &category.CreatedAt, &category.Version)
+	if err != nil {
+		return categoryError(err)
+	}
+	return nil
+}
+
+func (m *CategoryModel) Get(id int64) (*Category, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
+	}
+
+	qry := `
+		SELECT id, name, parent_id, position, created_at, version
+		FROM categories
+		WHERE id = $1`
+
+	var category Category
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, id).Scan(
+		&category.ID,
+		&category.Name,
+		&category.Parent,
+		&category.Position,
+		&category.CreatedAt,
+		&category.Version,
+	)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return &category, nil
+}
+
+// GetTree returns the top level categories with their descendants nested in
+// Children, each level in position order.
+func (m *CategoryModel) GetTree() ([]*Category, error) {
+	qry := `
+		SELECT id, name, parent_id, position, created_at, version
+		FROM categories
+		ORDER BY position ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	byID := map[int64]*Category{}
+
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.Parent,
+			&category.Position,
+			&category.CreatedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+		byID[category.ID] = &category
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots := []*Category{}
+
+	for _, category := range categories {
+		if category.Parent == nil {
+			roots = append(roots, category)
+			continue
+		}
+		parent := byID[*category.Parent]
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots, nil
+}
+
+// GetPath returns the ancestors of a category from the top level down,
+// followed by the category itself.
+func (m *CategoryModel) GetPath(id int64) ([]*Category, error) {
+	qry := `
+		WITH RECURSIVE path AS (
+			SELECT id, name, parent_id, position, created_at, version, 0 AS depth
+			FROM categories
+			WHERE id = $1
+			UNION ALL
+			SELECT categories.id, categories.name, categories.parent_id, categories.position, categories.created_at, categories.version, path.depth + 1
+			FROM categories
+			JOIN path ON categories.id = path.parent_id
+		)
+		SELECT id, name, parent_id, position, created_at, version
+		FROM path
+		ORDER BY depth DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	path := []*Category{}
+
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.Parent,
+			&category.Position,
+			&category.CreatedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(path) == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return path, nil
+}
+
+// Update renames a category. Its place in the tree is changed with Move.
+func (m *CategoryModel) Update(category *Category) error {
+	qry := `
+		UPDATE categories
+		SET name = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, category.Name, category.ID, category.Version).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return categoryError(err)
+		}
+	}
+	return nil
+}
+
+// Move makes a category the child of parent, or a top level category if
+// parent is nil, at the given position among its new siblings. Positions
+// past the end put it last. Moving within the same parent reorders it. The
+// new siblings, and the old ones when the parent changes, are renumbered from
+// zero so positions stay contiguous.
+func (m *CategoryModel) Move(category *Category, parent *int64, position int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Concurrent moves could otherwise each pass the cycle check below and
+	// together link two subtrees into a loop.
+	_, err = tx.ExecContext(ctx, `LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE`)
+	if err != nil {
+		return err
+	}
+
+	if parent != nil {
+		qry := `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $1
+				UNION ALL
+				SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+			)
+			SELECT EXISTS (SELECT 1 FROM categories WHERE id = $2), EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+		var exists, cycle bool
+
+		err = tx.QueryRowContext(ctx, qry, category.ID, *parent).Scan(&exists, &cycle)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !exists:
+			return ErrUnknownParent
+		case cycle:
+			return ErrCategoryCycle
+		}
+	}
+
+	qry := `
+		SELECT id
+		FROM categories
+		WHERE parent_id IS NOT DISTINCT FROM $1 AND id <> $2
+		ORDER BY position ASC, id ASC`
+
+	rows, err := tx.QueryContext(ctx, qry, parent, category.ID)
+	if err != nil {
+		return err
+	}
+
+	var siblings []int64
+
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		siblings = append(siblings, id)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if position < 0 || position > len(siblings) {
+		position = len(siblings)
+	}
+
+	order := make([]int64, 0, len(siblings)+1)
+	order = append(order, siblings[:position]...)
+	order = append(order, category.ID)
+	order = append(order, siblings[position:]...)
+
+	qry = `
+		UPDATE categories
+		SET parent_id = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, qry, parent, category.ID, category.Version).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return categoryError(err)
+		}
+	}
+
+	qry = `
+		UPDATE categories
+		SET position = ord.position - 1
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS ord(id, position)
+		WHERE categories.id = ord.id`
+
+	_, err = tx.ExecContext(ctx, qry, pq.Array(order))
+	if err != nil {
+		return err
+	}
+
+	sameParent := category.Parent == nil && parent == nil ||
+		category.Parent != nil && parent != nil && *category.Parent == *parent
+
+	if !sameParent {
+		qry = `
+			UPDATE categories
+			SET position = ord.position - 1
+			FROM (
+				SELECT id, row_number() OVER (ORDER BY position ASC, id ASC) AS position
+				FROM categories
+				WHERE parent_id IS NOT DISTINCT FROM $1
+			) AS ord
+			WHERE categories.id = ord.id AND categories.position <> ord.position - 1`
+
+		_, err = tx.ExecContext(ctx, qry, category.Parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	category.Parent = parent
+	category.Position = position
+
+	return nil
+}
+
+// Delete removes a category that has no subcategories or attribute
+// definitions and isn't assigned to any item, including items in the trash.
+func (m *CategoryModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrNoRecord
+	}
+
+	qry := `
+		DELETE FROM categories
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: update or delete on table "categories" violates foreign key constraint "categories_parent_id_fkey" on table "categories"`,
+			err.Error() == `pq: update or delete on table "categories" violates foreign key constraint "items_category_fkey" on table "items"`,
+			err.Error() == `pq: update or delete on table "categories" violates foreign key constraint "attribute_definitions_category_fkey" on table "attribute_definitions"`:
+			return ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Currency struct {
+	NumericCode int    `json:"numericCode"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Exponent    int    `json:"exponent"`
+	Symbol      string `json:"symbol"`
+}
+
+// CurrencyRegistry holds the supported currencies keyed by ISO 4217
+// alphabetic code. It is populated from the currencies table by
+// CurrencyModel.Load so that validation and price formatting don't need a
+// database round trip.
+type CurrencyRegistry struct {
+	mu         sync.RWMutex
+	currencies map[string]*Currency
+}
+
+// Lookup finds a currency by its code in any case, so "eur" finds EUR.
+func (r *CurrencyRegistry) Lookup(code string) (*Currency, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	currency, ok := r.currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return currency, ok
+}
+
+type CurrencyModel struct {
+	DB       *sql.DB
+	Registry *CurrencyRegistry
+}
+
+func (m *CurrencyModel) GetAll() ([]*Currency, error) {
+	qry := `
+		SELECT id, code, name, exponent, symbol
+		FROM currencies
+		ORDER BY code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currencies := []*Currency{}
+
+	for rows.Next() {
+		var currency Currency
+		err := rows.Scan(
+			&currency.NumericCode,
+			&currency.Code,
+			&currency.Name,
+			&currency.Exponent,
+			&currency.Symbol,
+		)
+		if err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, &currency)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return currencies, nil
+}
+
+func (m *CurrencyModel) Load() error {
+	currencies, err := m.GetAll()
+	if err != nil {
+		return err
+	}
+
+	byCode := make(map[string]*Currency, len(currencies))
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
+	}
+
+	m.Registry.mu.Lock()
+	m.Registry.currencies = byCode
+	m.Registry.mu.Unlock()
+
+	return nil
+}
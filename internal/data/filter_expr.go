@@ -0,0 +1,591 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxFilterTerms = 50
+	maxFilterDepth = 20
+)
+
+// FilterError reports a syntax or type error in a filter expression. Pos is
+// the position of the offending token, counting the first character as 1.
+type FilterError struct {
+	Pos int
+	Msg string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+type filterFieldType int
+
+const (
+	filterInt filterFieldType = iota
+	filterMoney
+	filterText
+	filterCode
+	filterTags
+	filterBool
+	filterTime
+	filterAttributes
+)
+
+// filterField maps a field name usable in filter expressions to the SQL
+// expression it's compared against. Columns are trusted; only values from the
+// expression are passed as arguments. A filterAttributes field is a JSON
+// object whose members are compared as <field>.<key>. A filterCode field is
+// text stored in upper case, such as a currency code, so values are
+// upper-cased before they are compared.
+type filterField struct {
+	column string
+	typ    filterFieldType
+}
+
+// FilterExpr is a parsed filter expression such as
+//
+//	price > 10 and currency = EUR and (tags:cable or supplier = 4) and not archived
+//
+// Comparisons are combined with and, or, not and parentheses. The operators
+// are = != < <= > >= and :, which matches text containing the value, ignoring
+// case, or tags including it. A boolean field on its own is true when set.
+//
+// Members of a JSON object field such as attributes.length take their type
+// from the value they're compared with: quoted values are strings, true and
+// false are booleans and other numeric values are numbers. Members of a
+// different type never match an ordering comparison.
+type FilterExpr struct {
+	root   filterNode
+	fields map[string]bool
+}
+
+// References reports whether the expression compares the named field.
+func (f *FilterExpr) References(field string) bool {
+	return f.fields[field]
+}
+
+// sql returns the expression as an SQL condition, appending its values to
+// args and referring to them by their position there.
+func (f *FilterExpr) sql(args *[]any) string {
+	return f.root.sql(args)
+}
+
+type filterNode interface {
+	sql(args *[]any) string
+}
+
+type filterAnd struct {
+	left, right filterNode
+}
+
+func (n filterAnd) sql(args *[]any) string {
+	return "(" + n.left.sql(args) + " AND " + n.right.sql(args) + ")"
+}
+
+type filterOr struct {
+	left, right filterNode
+}
+
+func (n filterOr) sql(args *[]any) string {
+	return "(" + n.left.sql(args) + " OR " + n.right.sql(args) + ")"
+}
+
+type filterNot struct {
+	node filterNode
+}
+
+func (n filterNot) sql(args *[]any) string {
+	return "NOT " + n.node.sql(args)
+}
+
+type filterCompare struct {
+	name  string
+	pos   int
+	field filterField
+	op    string
+	value any
+}
+
+func (n filterCompare) sql(args *[]any) string {
+	*args = append(*args, n.value)
+	placeholder := fmt.Sprintf("$%d", len(*args))
+
+	switch {
+	case n.field.typ == filterTags && n.op == "!=":
+		return fmt.Sprintf("NOT (%s @> %s)", n.field.column, placeholder)
+	case n.field.typ == filterTags:
+		return fmt.Sprintf("(%s @> %s)", n.field.column, placeholder)
+	case n.op == ":":
+		return fmt.Sprintf("(%s ILIKE %s)", n.field.column, placeholder)
+	case n.op == "!=":
+		return fmt.Sprintf("(%s <> %s)", n.field.column, placeholder)
+	default:
+		return fmt.Sprintf("(%s %s %s)", n.field.column, n.op, placeholder)
+	}
+}
+
+type filterAttributeCompare struct {
+	column string
+	key    string
+	op     string
+	value  any
+}
+
+func (n filterAttributeCompare) sql(args *[]any) string {
+	switch n.op {
+	case ":":
+		*args = append(*args, n.key, n.value)
+		return fmt.Sprintf("(%s->>$%d::text ILIKE $%d)", n.column, len(*args)-1, len(*args))
+	case "=", "!=":
+		// Containment is answered from the GIN index on the column.
+		js, _ := json.Marshal(map[string]any{n.key: n.value})
+		*args = append(*args, string(js))
+		condition := fmt.Sprintf("(%s @> $%d::jsonb)", n.column, len(*args))
+		if n.op == "!=" {
+			return "NOT " + condition
+		}
+		return condition
+	default:
+		js, _ := json.Marshal(n.value)
+		*args = append(*args, n.key, string(js))
+		member := fmt.Sprintf("%s->$%d::text", n.column, len(*args)-1)
+		return fmt.Sprintf("(jsonb_typeof(%s) = jsonb_typeof($%d::jsonb) AND %s %s $%d::jsonb)", member, len(*args), member, n.op, len(*args))
+	}
+}
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func isFilterWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-' || c == '+'
+}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		pos := i + 1
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "(", pos: pos})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")", pos: pos})
+			i++
+		case c == '=' || c == ':':
+			tokens = append(tokens, filterToken{kind: tokenOp, text: string(c), pos: pos})
+			i++
+		case c == '!' || c == '<' || c == '>':
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				tokens = append(tokens, filterToken{kind: tokenOp, text: expr[i : i+2], pos: pos})
+				i += 2
+				continue
+			}
+			if c == '!' {
+				return nil, &FilterError{Pos: pos, Msg: `expected "!="`}
+			}
+			tokens = append(tokens, filterToken{kind: tokenOp, text: string(c), pos: pos})
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, &FilterError{Pos: pos, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: sb.String(), pos: pos})
+			i = j + 1
+		case isFilterWordByte(c):
+			j := i
+			for j < len(expr) && isFilterWordByte(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: expr[i:j], pos: pos})
+			i = j
+		default:
+			return nil, &FilterError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+
+	tokens = append(tokens, filterToken{kind: tokenEOF, pos: len(expr) + 1})
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+	fields map[string]filterField
+	used   map[string]bool
+	terms  int
+	depth  int
+}
+
+// parseFilter parses expr, allowing comparisons on the given fields only.
+func parseFilter(expr string, fields map[string]filterField) (*FilterExpr, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens, fields: fields, used: map[string]bool{}}
+
+	if p.peek().kind == tokenEOF {
+		return nil, &FilterError{Pos: 1, Msg: "empty expression"}
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+
+	return &FilterExpr{root: root, fields: p.used}, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() filterToken {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, consuming it
+// if so. Keywords are case insensitive and can't be quoted.
+func (p *filterParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.keyword("not") {
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	t := p.advance()
+
+	switch t.kind {
+	case tokenLParen:
+		p.depth++
+		if p.depth > maxFilterDepth {
+			return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("must not nest more than %d parentheses deep", maxFilterDepth)}
+		}
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, &FilterError{Pos: closing.pos, Msg: "expected \")\""}
+		}
+
+		p.depth--
+		return node, nil
+	case tokenWord:
+		return p.parseComparison(t)
+	case tokenEOF:
+		return nil, &FilterError{Pos: t.pos, Msg: "unexpected end of expression"}
+	default:
+		return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("expected a field name, found %q", t.text)}
+	}
+}
+
+// field looks up a field by name. Members of JSON object fields are also
+// returned with their key.
+func (p *filterParser) field(name string) (filterField, string, bool) {
+	if field, ok := p.fields[name]; ok {
+		return field, "", field.typ != filterAttributes
+	}
+
+	base, key, found := strings.Cut(name, ".")
+	if !found {
+		return filterField{}, "", false
+	}
+
+	field, ok := p.fields[base]
+	if !ok || field.typ != filterAttributes || !AttributeNameRX.MatchString(key) {
+		return filterField{}, "", false
+	}
+
+	return field, key, true
+}
+
+func (p *filterParser) parseComparison(name filterToken) (filterNode, error) {
+	field, key, ok := p.field(name.text)
+	if !ok {
+		return nil, &FilterError{Pos: name.pos, Msg: fmt.Sprintf("unknown field %q", name.text)}
+	}
+
+	p.terms++
+	if p.terms > maxFilterTerms {
+		return nil, &FilterError{Pos: name.pos, Msg: fmt.Sprintf("must not contain more than %d comparisons", maxFilterTerms)}
+	}
+
+	p.used[name.text] = true
+
+	if field.typ == filterAttributes {
+		return p.parseAttributeComparison(name, field.column, key)
+	}
+
+	op := p.peek()
+	if op.kind != tokenOp {
+		if field.typ == filterBool {
+			return filterCompare{name: name.text, pos: name.pos, field: field, op: "=", value: true}, nil
+		}
+		return nil, &FilterError{Pos: op.pos, Msg: fmt.Sprintf("expected an operator after %q", name.text)}
+	}
+	p.advance()
+
+	if !filterOpAllowed(field.typ, op.text) {
+		return nil, &FilterError{Pos: op.pos, Msg: fmt.Sprintf("operator %q can't be used with %q", op.text, name.text)}
+	}
+
+	value := p.advance()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &FilterError{Pos: value.pos, Msg: fmt.Sprintf("expected a value for %q", name.text)}
+	}
+
+	v, msg := filterValue(field.typ, op.text, value.text)
+	if msg != "" {
+		return nil, &FilterError{Pos: value.pos, Msg: fmt.Sprintf("%s %s", name.text, msg)}
+	}
+
+	return filterCompare{name: name.text, pos: name.pos, field: field, op: op.text, value: v}, nil
+}
+
+// checkAmountCurrency returns a *FilterError for the first comparison of an
+// amount that isn't combined by and with an equality on the currency field,
+// since amounts in different currencies can't be compared. A currency
+// equality under not doesn't count. pinned reports whether the currency is
+// already fixed outside the expression.
+func checkAmountCurrency(node filterNode, currency string, pinned, negated bool) error {
+	switch n := node.(type) {
+	case filterAnd:
+		conjuncts := flattenAnd(n, nil)
+
+		if !negated {
+			for _, c := range conjuncts {
+				if cmp, ok := c.(filterCompare); ok && cmp.name == currency && cmp.op == "=" {
+					pinned = true
+				}
+			}
+		}
+
+		for _, c := range conjuncts {
+			err := checkAmountCurrency(c, currency, pinned, negated)
+			if err != nil {
+				return err
+			}
+		}
+	case filterOr:
+		err := checkAmountCurrency(n.left, currency, pinned, negated)
+		if err != nil {
+			return err
+		}
+		return checkAmountCurrency(n.right, currency, pinned, negated)
+	case filterNot:
+		return checkAmountCurrency(n.node, currency, pinned, !negated)
+	case filterCompare:
+		if n.field.typ == filterMoney && !pinned {
+			return &FilterError{Pos: n.pos, Msg: fmt.Sprintf("%s must be compared within one currency, add \"and %s = <code>\"", n.name, currency)}
+		}
+	}
+
+	return nil
+}
+
+// flattenAnd appends the operands of a chain of ands to nodes.
+func flattenAnd(node filterNode, nodes []filterNode) []filterNode {
+	if n, ok := node.(filterAnd); ok {
+		nodes = flattenAnd(n.left, nodes)
+		return flattenAnd(n.right, nodes)
+	}
+	return append(nodes, node)
+}
+
+func (p *filterParser) parseAttributeComparison(name filterToken, column, key string) (filterNode, error) {
+	op := p.peek()
+	if op.kind != tokenOp {
+		return filterAttributeCompare{column: column, key: key, op: "=", value: true}, nil
+	}
+	p.advance()
+
+	value := p.advance()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &FilterError{Pos: value.pos, Msg: fmt.Sprintf("expected a value for %q", name.text)}
+	}
+
+	if op.text == ":" {
+		return filterAttributeCompare{column: column, key: key, op: op.text, value: "%" + likeEscaper.Replace(value.text) + "%"}, nil
+	}
+
+	v := attributeFilterValue(value)
+
+	if _, isBool := v.(bool); isBool && op.text != "=" && op.text != "!=" {
+		return nil, &FilterError{Pos: op.pos, Msg: fmt.Sprintf("operator %q can't be used with true or false", op.text)}
+	}
+
+	return filterAttributeCompare{column: column, key: key, op: op.text, value: v}, nil
+}
+
+// attributeFilterValue types the value an attribute is compared with by how
+// it was written.
+func attributeFilterValue(t filterToken) any {
+	if t.kind == tokenString {
+		return t.text
+	}
+
+	if t.text == "true" || t.text == "false" {
+		return t.text == "true"
+	}
+
+	if f, err := strconv.ParseFloat(t.text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+
+	return t.text
+}
+
+func filterOpAllowed(typ filterFieldType, op string) bool {
+	switch typ {
+	case filterText, filterCode:
+		return true
+	case filterTags, filterBool:
+		return op == "=" || op == "!=" || (typ == filterTags && op == ":")
+	default:
+		return op != ":"
+	}
+}
+
+// filterValue converts the text of a value to the argument compared with a
+// field of the given type, or returns a message saying why it can't be.
+func filterValue(typ filterFieldType, op, text string) (any, string) {
+	switch typ {
+	case filterInt:
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, "must be compared with an integer"
+		}
+		return i, ""
+	case filterMoney:
+		m, err := ParseMoney(text)
+		if err != nil {
+			return nil, "must be compared with a decimal amount"
+		}
+		return m, ""
+	case filterBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, "must be compared with true or false"
+		}
+		return b, ""
+	case filterTime:
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", text)
+		}
+		if err != nil {
+			return nil, "must be compared with a date (YYYY-MM-DD) or a quoted RFC 3339 timestamp"
+		}
+		return t, ""
+	case filterTags:
+		return pq.Array([]string{text}), ""
+	case filterCode:
+		if op == ":" {
+			return "%" + likeEscaper.Replace(text) + "%", ""
+		}
+		return strings.ToUpper(text), ""
+	default:
+		if op == ":" {
+			return "%" + likeEscaper.Replace(text) + "%", ""
+		}
+		return text, ""
+	}
+}
@@ -0,0 +1,145 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseItemFilterPriceCurrency(t *testing.T) {
+	safelist := []string{"name", "price", "currency", "archived"}
+
+	tests := []struct {
+		name          string
+		expr          string
+		currencyFixed bool
+		pos           int
+	}{
+		{name: "alongside currency", expr: "price > 10 and currency = EUR"},
+		{name: "currency first", expr: "currency = EUR and price > 10"},
+		{name: "currency outside parentheses", expr: "currency = EUR and (price > 10 or name:drill)"},
+		{name: "negated price", expr: "currency = EUR and not price > 10"},
+		{name: "each branch pinned", expr: "(price > 10 and currency = EUR) or (price > 5 and currency = GBP)"},
+		{name: "fixed by the query", expr: "price > 10", currencyFixed: true},
+		{name: "no currency", expr: "price > 10", pos: 1},
+		{name: "currency in another branch", expr: "price > 10 or currency = EUR", pos: 1},
+		{name: "currency not equal", expr: "price > 10 and currency != EUR", pos: 1},
+		{name: "negated currency", expr: "price > 10 and not currency = EUR", pos: 1},
+		{name: "currency inside not", expr: "not (price > 10 and currency = EUR)", pos: 6},
+		{name: "one branch unpinned", expr: "(price > 10 and currency = EUR) or price > 5", pos: 36},
+		{name: "no price", expr: "name:drill and not archived"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseItemFilter(tt.expr, safelist, tt.currencyFixed)
+
+			if tt.pos == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("got error %v; want a *FilterError", err)
+			}
+			if filterErr.Pos != tt.pos {
+				t.Errorf("got position %d; want %d", filterErr.Pos, tt.pos)
+			}
+		})
+	}
+}
+
+func TestParseItemFilterErrors(t *testing.T) {
+	safelist := []string{"name", "supplier", "currency", "archived", "created_at", "attributes"}
+
+	tests := []struct {
+		name string
+		expr string
+		pos  int
+		msg  string
+	}{
+		{name: "empty", expr: "  ", pos: 1, msg: "empty expression"},
+		{name: "unterminated string", expr: "name = 'drill", pos: 8, msg: "unterminated string"},
+		{name: "lone bang", expr: "name ! drill", pos: 6, msg: `expected "!="`},
+		{name: "unexpected character", expr: "name = drill & archived", pos: 14, msg: "unexpected character '&'"},
+		{name: "trailing and", expr: "name = drill and", pos: 17, msg: "unexpected end of expression"},
+		{name: "unclosed parenthesis", expr: "(name = drill", pos: 14, msg: `expected ")"`},
+		{name: "extra parenthesis", expr: "name = drill)", pos: 13, msg: `unexpected ")"`},
+		{name: "operator first", expr: "= drill", pos: 1, msg: "expected a field name"},
+		{name: "unknown field", expr: "archived and colour = red", pos: 14, msg: `unknown field "colour"`},
+		{name: "field not in safelist", expr: "model = D-1", pos: 1, msg: `unknown field "model"`},
+		{name: "missing operator", expr: "name drill", pos: 6, msg: `expected an operator after "name"`},
+		{name: "missing value", expr: "name =", pos: 7, msg: `expected a value for "name"`},
+		{name: "contains on integer", expr: "supplier : 4", pos: 10, msg: `operator ":" can't be used with "supplier"`},
+		{name: "ordering on boolean", expr: "archived < true", pos: 10, msg: `operator "<" can't be used with "archived"`},
+		{name: "text for integer", expr: "supplier = acme", pos: 12, msg: "supplier must be compared with an integer"},
+		{name: "bad boolean", expr: "archived = yes", pos: 12, msg: "archived must be compared with true or false"},
+		{name: "bad date", expr: "created_at > yesterday", pos: 14, msg: "created_at must be compared with a date"},
+		{name: "bare object field", expr: "attributes = 1", pos: 1, msg: `unknown field "attributes"`},
+		{name: "invalid attribute name", expr: "attributes.Length = 4", pos: 1, msg: `unknown field "attributes.Length"`},
+		{name: "ordering on attribute boolean", expr: "attributes.cordless > true", pos: 21, msg: `operator ">" can't be used with true or false`},
+		{name: "missing attribute value", expr: "attributes.length >", pos: 20, msg: `expected a value for "attributes.length"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseItemFilter(tt.expr, safelist, true)
+
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("got error %v; want a *FilterError", err)
+			}
+			if filterErr.Pos != tt.pos {
+				t.Errorf("got position %d; want %d", filterErr.Pos, tt.pos)
+			}
+			if !strings.Contains(filterErr.Msg, tt.msg) {
+				t.Errorf("got message %q; want it to contain %q", filterErr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseItemFilterSQL(t *testing.T) {
+	safelist := []string{"name", "currency", "archived", "tags", "attributes"}
+
+	tests := []struct {
+		name string
+		expr string
+		sql  string
+		args []any
+	}{
+		{name: "lower case currency", expr: "currency = eur", sql: "((SELECT code FROM currencies WHERE currencies.id = items.currency) = $1)", args: []any{"EUR"}},
+		{name: "mixed case currency", expr: "currency != 'Gbp'", sql: "((SELECT code FROM currencies WHERE currencies.id = items.currency) <> $1)", args: []any{"GBP"}},
+		{name: "currency contains", expr: "currency:eu", sql: "((SELECT code FROM currencies WHERE currencies.id = items.currency) ILIKE $1)", args: []any{"%eu%"}},
+		{name: "text keeps case", expr: "name = Drill", sql: "(name = $1)", args: []any{"Drill"}},
+		{name: "contains escapes wildcards", expr: "name:'50%_off'", sql: "(name ILIKE $1)", args: []any{`%50\%\_off%`}},
+		{name: "bare boolean", expr: "not archived", sql: "NOT (archived = $1)", args: []any{true}},
+		{name: "attribute number", expr: "attributes.length >= 10", sql: "(jsonb_typeof(attributes->$1::text) = jsonb_typeof($2::jsonb) AND attributes->$1::text >= $2::jsonb)", args: []any{"length", "10"}},
+		{name: "attribute quoted string", expr: "attributes.colour = '10'", sql: "(attributes @> $1::jsonb)", args: []any{`{"colour":"10"}`}},
+		{name: "attribute not equal", expr: "attributes.colour != red", sql: "NOT (attributes @> $1::jsonb)", args: []any{`{"colour":"red"}`}},
+		{name: "bare attribute", expr: "attributes.cordless", sql: "(attributes @> $1::jsonb)", args: []any{`{"cordless":true}`}},
+		{name: "attribute contains", expr: "attributes.colour:re", sql: "(attributes->>$1::text ILIKE $2)", args: []any{"colour", "%re%"}},
+		{name: "precedence", expr: "name = a or name = b and archived", sql: "((name = $1) OR ((name = $2) AND (archived = $3)))", args: []any{"a", "b", true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseItemFilter(tt.expr, safelist, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var args []any
+			if got := filter.sql(&args); got != tt.sql {
+				t.Errorf("got SQL %s; want %s", got, tt.sql)
+			}
+			if got, want := fmt.Sprint(args), fmt.Sprint(tt.args); got != want {
+				t.Errorf("got args %s; want %s", got, want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"strings"
 
@@ -12,14 +16,56 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	// Cursor switches a listing from offset to keyset pagination when set.
+	// A zero Cursor asks for the first page.
+	Cursor *Cursor
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last row of a keyset paginated page: its
+// value in the sort column and its id. Clients only see it encoded, as an
+// opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor reads a cursor produced by Encode. An empty string is the
+// cursor for the first page.
+func DecodeCursor(s string) (*Cursor, error) {
+	var c Cursor
+
+	if s == "" {
+		return &c, nil
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.Sort == "" || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -28,6 +74,10 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize > 0, "page_size", "must be a positive integer")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if f.Cursor != nil && f.Cursor.ID > 0 {
+		v.Check(f.Cursor.Sort == f.Sort, "cursor", "does not match the sort order")
+	}
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -67,3 +117,16 @@ func (f Filters) limit() int {
 func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
+
+// keysetCondition returns the condition for rows that sort after a cursor
+// on the given sort expression and direction, given the placeholder numbers
+// of the cursor's value and id. Ties are broken by id, which is always
+// ascending.
+func keysetCondition(column, direction string, valueArg, idArg int) string {
+	op := ">"
+	if direction == "DESC" {
+		op = "<"
+	}
+
+	return fmt.Sprintf("AND ((%s) %s $%d OR ((%s) = $%d AND id > $%d))", column, op, valueArg, column, valueArg, idArg)
+}
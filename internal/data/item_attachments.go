@@ -0,0 +1,255 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+type ItemAttachment struct {
+	ID          int64     `json:"id"`
+	ItemID      int64     `json:"itemId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	StorageKey  string    `json:"-"`
+	UploadedBy  *int64    `json:"uploadedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type attachmentType struct {
+	extensions []string
+	// sniffed lists the types http.DetectContentType may report for the
+	// format. Office documents are zip or OLE containers it can't see inside.
+	sniffed []string
+	// magic, if set, is a signature the content must also start with, for
+	// formats http.DetectContentType only reports as application/octet-stream.
+	magic []byte
+}
+
+// oleMagic starts every OLE2 compound file, the container of legacy Office
+// documents.
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var attachmentTypes = map[string]attachmentType{
+	"application/pdf": {[]string{".pdf"}, []string{"application/pdf"}, nil},
+	"text/plain":      {[]string{".txt"}, []string{"text/plain; charset=utf-8", "text/plain; charset=utf-16le", "text/plain; charset=utf-16be"}, nil},
+	"text/csv":        {[]string{".csv"}, []string{"text/plain; charset=utf-8", "text/plain; charset=utf-16le", "text/plain; charset=utf-16be"}, nil},
+	"image/png":       {[]string{".png"}, []string{"image/png"}, nil},
+	"image/jpeg":      {[]string{".jpg", ".jpeg"}, []string{"image/jpeg"}, nil},
+	"application/zip": {[]string{".zip"}, []string{"application/zip"}, nil},
+	"application/vnd.ms-excel": {
+		[]string{".xls"}, []string{"application/octet-stream"}, oleMagic,
+	},
+	"application/msword": {
+		[]string{".doc"}, []string{"application/octet-stream"}, oleMagic,
+	},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {
+		[]string{".xlsx"}, []string{"application/zip"}, nil,
+	},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": {
+		[]string{".docx"}, []string{"application/zip"}, nil,
+	},
+}
+
+// AttachmentContentType works out the content type of an uploaded file. A
+// declared type is used when it is one we accept, otherwise the type is
+// inferred from the file name's extension. It returns "" if neither gives an
+// accepted type.
+func AttachmentContentType(declared, filename string) string {
+	if _, ok := attachmentTypes[declared]; ok {
+		return declared
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+	for contentType, t := range attachmentTypes {
+		if validator.PermittedValue(ext, t.extensions...) {
+			return contentType
+		}
+	}
+
+	return ""
+}
+
+// CleanAttachmentFilename reduces a client supplied file name to its base name
+// with control characters removed.
+func CleanAttachmentFilename(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename)
+	filename = strings.TrimSpace(filename)
+
+	if filename == "." || filename == "/" {
+		return ""
+	}
+	return filename
+}
+
+// ValidateItemAttachment checks an attachment's metadata against its content.
+// Content is only sniffed and checked for a signature, the file is not
+// otherwise parsed.
+func ValidateItemAttachment(v *validator.Validator, attachment *ItemAttachment, content []byte) {
+	v.Check(attachment.Filename != "", "filename", "must be provided")
+	v.Check(len(attachment.Filename) <= 255, "filename", "must not be more than 255 bytes long")
+
+	t, ok := attachmentTypes[attachment.ContentType]
+	v.Check(ok, "file", "must be a PDF, spreadsheet, document, text, CSV, image or zip file")
+
+	if ok {
+		v.Check(validator.PermittedValue(http.DetectContentType(content), t.sniffed...) && bytes.HasPrefix(content, t.magic), "file", "content does not match its type")
+	}
+}
+
+type ItemAttachmentModel struct {
+	DB *sql.DB
+}
+
+func (m *ItemAttachmentModel) Insert(attachment *ItemAttachment) error {
+	qry := `
+		INSERT INTO item_attachments (item_id, filename, content_type, size, checksum, storage_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	args := []any{
+		attachment.ItemID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.StorageKey,
+		attachment.UploadedBy,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, qry, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (m *ItemAttachmentModel) Get(itemID, id int64) (*ItemAttachment, error) {
+	qry := `
+		SELECT id, item_id, filename, content_type, size, checksum, storage_key, uploaded_by, created_at
+		FROM item_attachments
+		WHERE id = $1 AND item_id = $2`
+
+	var attachment ItemAttachment
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, id, itemID).Scan(
+		&attachment.ID,
+		&attachment.ItemID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.StorageKey,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &attachment, nil
+}
+
+func (m *ItemAttachmentModel) GetAllForItem(itemID int64) ([]*ItemAttachment, error) {
+	qry := `
+		SELECT id, item_id, filename, content_type, size, checksum, storage_key, uploaded_by, created_at
+		FROM item_attachments
+		WHERE item_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*ItemAttachment{}
+
+	for rows.Next() {
+		var attachment ItemAttachment
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.ItemID,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.Checksum,
+			&attachment.StorageKey,
+			&attachment.UploadedBy,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, &attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes an attachment's record and returns it so the caller can
+// remove the stored file.
+func (m *ItemAttachmentModel) Delete(itemID, id int64) (*ItemAttachment, error) {
+	qry := `
+		DELETE FROM item_attachments
+		WHERE id = $1 AND item_id = $2
+		RETURNING id, item_id, filename, content_type, size, checksum, storage_key, uploaded_by, created_at`
+
+	var attachment ItemAttachment
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, id, itemID).Scan(
+		&attachment.ID,
+		&attachment.ItemID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.StorageKey,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &attachment, nil
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ItemTx groups item writes into a single transaction. Every write records
+// price history and revisions exactly as the equivalent ItemModel method
+// does. A failed write aborts the transaction, so the caller must roll back
+// after any error.
+type ItemTx struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	tx     *sql.Tx
+}
+
+// Begin starts a transaction for a batch of item writes. The whole batch must
+// complete within the timeout.
+func (m *ItemModel) Begin(timeout time.Duration) (*ItemTx, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &ItemTx{ctx: ctx, cancel: cancel, tx: tx}, nil
+}
+
+func (t *ItemTx) Get(id int64) (*Item, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
+	}
+	return getItem(t.ctx, t.tx, id)
+}
+
+// ErrAmbiguousItemKey is returned by GetByKey when more than one item matches.
+var ErrAmbiguousItemKey = errors.New("more than one item matches the key")
+
+// ItemKeyFields are the fields GetByKey can match items on.
+var ItemKeyFields = []string{"id", "name", "model", "supplier"}
+
+// GetByKey finds the single item whose key fields equal those of item. Every
+// field in key must be one of ItemKeyFields.
+func (t *ItemTx) GetByKey(key []string, item *Item) (*Item, error) {
+	var conditions []string
+	var args []any
+
+	for _, field := range key {
+		switch field {
+		case "id":
+			args = append(args, item.ID)
+		case "name":
+			args = append(args, item.Name)
+		case "model":
+			args = append(args, item.Model)
+		case "supplier":
+			args = append(args, item.Supplier)
+		default:
+			panic("unsafe item key field: " + field)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", field, len(args)))
+	}
+
+	qry := `
+		SELECT id
+		FROM items
+		WHERE deleted_at IS NULL AND ` + strings.Join(conditions, " AND ") + `
+		LIMIT 2`
+
+	rows, err := t.tx.QueryContext(t.ctx, qry, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, ErrNoRecord
+	case 1:
+		return getItem(t.ctx, t.tx, ids[0])
+	default:
+		return nil, ErrAmbiguousItemKey
+	}
+}
+
+// AttributeSchema returns the attribute definitions that apply to items in
+// category, for validating items written in the transaction.
+func (t *ItemTx) AttributeSchema(category *int64) (AttributeSchema, error) {
+	return attributeSchema(t.ctx, t.tx, category)
+}
+
+func (t *ItemTx) Insert(item *Item, userID int64) error {
+	return insertItem(t.ctx, t.tx, item, userID)
+}
+
+func (t *ItemTx) Update(item *Item, userID int64) error {
+	return updateItem(t.ctx, t.tx, item, RevisionUpdate, userID)
+}
+
+func (t *ItemTx) Delete(id, userID int64) error {
+	return deleteItem(t.ctx, t.tx, id, userID)
+}
+
+func (t *ItemTx) Commit() error {
+	defer t.cancel()
+	return t.tx.Commit()
+}
+
+// Rollback abandons the transaction. It is safe to call after Commit.
+func (t *ItemTx) Rollback() error {
+	defer t.cancel()
+
+	err := t.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ItemFacetNames are the facets Facets can count items by.
+var ItemFacetNames = []string{"tags", "supplier", "currency", "price"}
+
+// maxFacetValues caps how many values of the tags and supplier facets are
+// counted; the most common ones are kept.
+const maxFacetValues = 20
+
+// priceBands are the lower bounds of the price facet's bands after the first,
+// which starts at zero, in minor units of the currency. Bands include their
+// lower bound but not their upper. Counting minor units scales the bands by
+// the currency's exponent: they start at 10 for currencies with two decimal
+// places such as EUR, 1000 for JPY and 1 for BHD.
+var priceBands = []int64{1000, 5000, 10000, 50000, 100000}
+
+// priceBandsSQL returns priceBands as a comma separated list.
+func priceBandsSQL() string {
+	bands := make([]string, len(priceBands))
+	for i, band := range priceBands {
+		bands[i] = strconv.FormatInt(band, 10)
+	}
+	return strings.Join(bands, ", ")
+}
+
+// priceBandBounds returns the bounds of a price facet band, as numbered by
+// width_bucket over priceBands, for a currency with the given exponent. The
+// last band has no upper bound.
+func priceBandBounds(band, exponent int) (string, string) {
+	min, max := Money{scale: exponent}.String(), ""
+	if band > 0 {
+		min = Money{units: priceBands[band-1], scale: exponent}.String()
+	}
+	if band < len(priceBands) {
+		max = Money{units: priceBands[band], scale: exponent}.String()
+	}
+	return min, max
+}
+
+// FacetCount is the number of matching items with one value of a facet. Price
+// bands are counted per currency and give their bounds in Min and Max; the
+// last band has no Max.
+type FacetCount struct {
+	Value    string `json:"value"`
+	Label    string `json:"label,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Min      string `json:"min,omitempty"`
+	Max      string `json:"max,omitempty"`
+	Count    int    `json:"count"`
+}
+
+type ItemFacets map[string][]*FacetCount
+
+// itemFacetQueries select facet, value, label, currency, price band, the
+// currency's exponent and count for each facet from the matched items.
+var itemFacetQueries = map[string]string{
+	"tags": `(
+			SELECT 'tags', tag, '', '', 0, 0, count(*)
+			FROM matched, unnest(matched.tags) AS tag
+			GROUP BY tag
+			ORDER BY count(*) DESC, tag ASC
+			LIMIT ` + fmt.Sprint(maxFacetValues) + `)`,
+	"supplier": `(
+			SELECT 'supplier', matched.supplier::text, COALESCE(suppliers.name, ''), '', 0, 0, count(*)
+			FROM matched
+			LEFT JOIN suppliers ON suppliers.id = matched.supplier
+			GROUP BY matched.supplier, suppliers.name
+			ORDER BY count(*) DESC, matched.supplier ASC
+			LIMIT ` + fmt.Sprint(maxFacetValues) + `)`,
+	"currency": `(
+			SELECT 'currency', COALESCE(currencies.code, ''), COALESCE(currencies.name, ''), '', 0, 0, count(*)
+			FROM matched
+			LEFT JOIN currencies ON currencies.id = matched.currency
+			GROUP BY currencies.code, currencies.name
+			ORDER BY count(*) DESC, currencies.code ASC)`,
+	"price": `(
+			SELECT 'price', '', '', COALESCE(currencies.code, ''), width_bucket(matched.price * power(10::numeric, COALESCE(currencies.exponent, 2)), ARRAY[` + priceBandsSQL() + `]::numeric[]), COALESCE(currencies.exponent, 2), count(*)
+			FROM matched
+			LEFT JOIN currencies ON currencies.id = matched.currency
+			GROUP BY currencies.code, 5, 6
+			ORDER BY currencies.code ASC, 5 ASC)`,
+}
+
+// Facets counts the items matching query by each of the named facets, which
+// must be in ItemFacetNames. Every named facet is in the result, empty if no
+// item matches.
+func (m *ItemModel) Facets(query ItemQuery, names []string) (ItemFacets, error) {
+	facets := ItemFacets{}
+	selects := make([]string, 0, len(names))
+
+	for _, name := range names {
+		qry, ok := itemFacetQueries[name]
+		if !ok {
+			panic("unknown item facet: " + name)
+		}
+
+		if _, seen := facets[name]; !seen {
+			facets[name] = []*FacetCount{}
+			selects = append(selects, qry)
+		}
+	}
+
+	if len(selects) == 0 {
+		return facets, nil
+	}
+
+	conditions, args := query.conditions()
+
+	qry := fmt.Sprintf(`
+		WITH matched AS (
+			SELECT supplier, currency, price, tags
+			FROM items
+			%s
+		)
+		%s`, conditions, strings.Join(selects, "\n\t\tUNION ALL "))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, done, err := m.queryItems(ctx, query, qry, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	for rows.Next() {
+		var name string
+		var band, exponent int
+		var count FacetCount
+
+		err := rows.Scan(&name, &count.Value, &count.Label, &count.Currency, &band, &exponent, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "price" {
+			count.Min, count.Max = priceBandBounds(band, exponent)
+			count.Value = count.Min + ".." + count.Max
+		}
+
+		facets[name] = append(facets[name], &count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
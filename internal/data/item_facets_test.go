@@ -0,0 +1,32 @@
+package data
+
+import "testing"
+
+func TestPriceBandBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		band     int
+		exponent int
+		min, max string
+	}{
+		{name: "first band, two decimals", band: 0, exponent: 2, min: "0.00", max: "10.00"},
+		{name: "middle band, two decimals", band: 2, exponent: 2, min: "50.00", max: "100.00"},
+		{name: "last band, two decimals", band: 5, exponent: 2, min: "1000.00", max: ""},
+		{name: "first band, no decimals", band: 0, exponent: 0, min: "0", max: "1000"},
+		{name: "last band, no decimals", band: 5, exponent: 0, min: "100000", max: ""},
+		{name: "middle band, three decimals", band: 3, exponent: 3, min: "10.000", max: "50.000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, max := priceBandBounds(tt.band, tt.exponent)
+			if min != tt.min || max != tt.max {
+				t.Errorf("got %q..%q; want %q..%q", min, max, tt.min, tt.max)
+			}
+		})
+	}
+
+	if got, want := priceBandsSQL(), "1000, 5000, 10000, 50000, 100000"; got != want {
+		t.Errorf("got bands %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,316 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+const (
+	PriceChangePending   = "pending"
+	PriceChangeApplied   = "applied"
+	PriceChangeCancelled = "cancelled"
+	PriceChangeFailed    = "failed"
+)
+
+type ItemPriceChange struct {
+	ID          int64      `json:"id"`
+	ItemID      int64      `json:"itemId"`
+	Price       Price      `json:"price"`
+	Currency    string     `json:"currency"`
+	EffectiveAt time.Time  `json:"effectiveAt"`
+	Status      string     `json:"status"`
+	CreatedBy   *int64     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	AppliedAt   *time.Time `json:"appliedAt"`
+}
+
+func ValidateItemPriceChange(v *validator.Validator, change *ItemPriceChange, currencies *CurrencyRegistry) {
+	validatePrice(v, currencies, &change.Price, &change.Currency)
+
+	v.Check(!change.EffectiveAt.IsZero(), "effectiveAt", "must be provided")
+	v.Check(change.EffectiveAt.After(time.Now()), "effectiveAt", "must be in the future")
+}
+
+type ItemPriceChangeModel struct {
+	DB *sql.DB
+}
+
+func (m *ItemPriceChangeModel) Insert(change *ItemPriceChange) error {
+	qry := `
+		INSERT INTO item_price_changes (item_id, amount, currency, effective_at, created_by)
+		VALUES ($1, $2, (SELECT id FROM currencies WHERE code = $3), $4, NULLIF($5, 0))
+		RETURNING id, status, created_at`
+
+	var createdBy int64
+	if change.CreatedBy != nil {
+		createdBy = *change.CreatedBy
+	}
+
+	args := []interface{}{change.ItemID, change.Price.Amount, change.Currency, change.EffectiveAt, createdBy}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&change.ID, &change.Status, &change.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAllForItem returns the item's scheduled price changes, soonest first.
+// An empty status returns changes in every state.
+func (m *ItemPriceChangeModel) GetAllForItem(itemID int64, status string) ([]*ItemPriceChange, error) {
+	qry := `
+		SELECT id, item_id, round(amount, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = item_price_changes.currency), 4)), COALESCE((SELECT code FROM currencies WHERE currencies.id = item_price_changes.currency), ''), effective_at, status, created_by, created_at, applied_at
+		FROM item_price_changes
+		WHERE item_id = $1
+		AND (status = $2 OR $2 = '')
+		ORDER BY effective_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, itemID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*ItemPriceChange{}
+
+	for rows.Next() {
+		var change ItemPriceChange
+		err := rows.Scan(
+			&change.ID,
+			&change.ItemID,
+			&change.Price.Amount,
+			&change.Currency,
+			&change.EffectiveAt,
+			&change.Status,
+			&change.CreatedBy,
+			&change.CreatedAt,
+			&change.AppliedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Cancel marks a pending change as cancelled. Changes that have already been
+// applied or cancelled are reported as ErrNoRecord.
+func (m *ItemPriceChangeModel) Cancel(itemID, id int64) error {
+	qry := `
+		UPDATE item_price_changes
+		SET status = $1
+		WHERE id = $2 AND item_id = $3 AND status = $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, PriceChangeCancelled, id, itemID, PriceChangePending)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// ApplyDue applies every pending change whose effective time has passed and
+// returns how many were applied. Each change is applied in its own
+// transaction through the normal item update path. A change for an item that
+// has since been deleted is cancelled, and one that can't be applied is
+// marked failed, so neither keeps its place at the front of the queue. The
+// error describes the failed changes once the rest have been applied.
+func (m *ItemPriceChangeModel) ApplyDue(now time.Time) (int, error) {
+	qry := `
+		SELECT id
+		FROM item_price_changes
+		WHERE status = $1 AND effective_at <= $2
+		ORDER BY effective_at ASC, id ASC
+		LIMIT 100`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, PriceChangePending, now)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	var failures []string
+
+	for _, id := range ids {
+		err := m.apply(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNoRecord):
+			default:
+				failErr := m.fail(id)
+				if failErr != nil {
+					return applied, failErr
+				}
+				failures = append(failures, fmt.Sprintf("change %d: %v", id, err))
+			}
+			continue
+		}
+		applied++
+	}
+
+	if len(failures) > 0 {
+		return applied, fmt.Errorf("failed to apply scheduled price changes: %s", strings.Join(failures, "; "))
+	}
+
+	return applied, nil
+}
+
+// fail marks a pending change that couldn't be applied as failed.
+func (m *ItemPriceChangeModel) fail(id int64) error {
+	qry := `
+		UPDATE item_price_changes
+		SET status = $1
+		WHERE id = $2 AND status = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, qry, PriceChangeFailed, id, PriceChangePending)
+	return err
+}
+
+func (m *ItemPriceChangeModel) apply(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qry := `
+		SELECT item_id, round(amount, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = item_price_changes.currency), 4)), COALESCE((SELECT code FROM currencies WHERE currencies.id = item_price_changes.currency), ''), effective_at, created_by
+		FROM item_price_changes
+		WHERE id = $1 AND status = $2
+		FOR UPDATE SKIP LOCKED`
+
+	var change ItemPriceChange
+
+	err = tx.QueryRowContext(ctx, qry, id, PriceChangePending).Scan(
+		&change.ItemID,
+		&change.Price.Amount,
+		&change.Currency,
+		&change.EffectiveAt,
+		&change.CreatedBy,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNoRecord
+		default:
+			return err
+		}
+	}
+
+	// Locking the item before reading it means the update below can't lose
+	// an edit conflict.
+	_, err = tx.ExecContext(ctx, `SELECT 1 FROM items WHERE id = $1 FOR UPDATE`, change.ItemID)
+	if err != nil {
+		return err
+	}
+
+	item, err := getItem(ctx, tx, change.ItemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoRecord):
+			_, err = tx.ExecContext(ctx, `UPDATE item_price_changes SET status = $1 WHERE id = $2`, PriceChangeCancelled, id)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Commit()
+			if err != nil {
+				return err
+			}
+
+			return ErrNoRecord
+		default:
+			return err
+		}
+	}
+
+	item.Price = change.Price
+	item.Currency = change.Currency
+
+	var userID int64
+	if change.CreatedBy != nil {
+		userID = *change.CreatedBy
+	}
+
+	// The history records the price as taking effect when the change was
+	// due rather than when it was applied, which leaves updateItem nothing
+	// to record.
+	err = recordItemPrice(ctx, tx, item, change.EffectiveAt, userID)
+	if err != nil {
+		return err
+	}
+
+	err = updateItem(ctx, tx, item, RevisionUpdate, userID)
+	if err != nil {
+		return err
+	}
+
+	qry = `
+		UPDATE item_price_changes
+		SET status = $1, applied_at = NOW()
+		WHERE id = $2`
+
+	_, err = tx.ExecContext(ctx, qry, PriceChangeApplied, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type ItemPrice struct {
+	ID            int64     `json:"id"`
+	ItemID        int64     `json:"itemId"`
+	Price         Price     `json:"price"`
+	Currency      string    `json:"currency"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	ChangedBy     *int64    `json:"changedBy"`
+}
+
+// recordItemPrice appends the item's current price to its price history as
+// taking effect at effectiveFrom, unless it is the same as the most recent
+// entry.
+func recordItemPrice(ctx context.Context, tx *sql.Tx, item *Item, effectiveFrom time.Time, userID int64) error {
+	qry := `
+		INSERT INTO item_prices (item_id, amount, currency, effective_from, changed_by)
+		SELECT $1, $2, currencies.id, $5, NULLIF($4, 0)
+		FROM currencies
+		WHERE currencies.code = $3
+		AND (
+			SELECT (amount, currency)
+			FROM item_prices
+			WHERE item_id = $1
+			ORDER BY effective_from DESC, id DESC
+			LIMIT 1
+		) IS DISTINCT FROM ($2::numeric, currencies.id)`
+
+	_, err := tx.ExecContext(ctx, qry, item.ID, item.Price.Amount, item.Currency, userID, effectiveFrom)
+	return err
+}
+
+type ItemPriceModel struct {
+	DB *sql.DB
+}
+
+func (m *ItemPriceModel) GetAllForItem(itemID int64) ([]*ItemPrice, error) {
+	qry := `
+		SELECT id, item_id, round(amount, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = item_prices.currency), 4)), COALESCE((SELECT code FROM currencies WHERE currencies.id = item_prices.currency), ''), effective_from, changed_by
+		FROM item_prices
+		WHERE item_id = $1
+		ORDER BY effective_from DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*ItemPrice{}
+
+	for rows.Next() {
+		var price ItemPrice
+		err := rows.Scan(
+			&price.ID,
+			&price.ItemID,
+			&price.Price.Amount,
+			&price.Currency,
+			&price.EffectiveFrom,
+			&price.ChangedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		prices = append(prices, &price)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// GetAsOf returns the price that was in effect for the item at the given
+// time, or ErrNoRecord if the item had no price yet.
+func (m *ItemPriceModel) GetAsOf(itemID int64, asOf time.Time) (*ItemPrice, error) {
+	qry := `
+		SELECT id, item_id, round(amount, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = item_prices.currency), 4)), COALESCE((SELECT code FROM currencies WHERE currencies.id = item_prices.currency), ''), effective_from, changed_by
+		FROM item_prices
+		WHERE item_id = $1 AND effective_from <= $2
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1`
+
+	var price ItemPrice
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, itemID, asOf).Scan(
+		&price.ID,
+		&price.ItemID,
+		&price.Price.Amount,
+		&price.Currency,
+		&price.EffectiveFrom,
+		&price.ChangedBy,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &price, nil
+}
@@ -0,0 +1,308 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	RevisionCreate    = "create"
+	RevisionUpdate    = "update"
+	RevisionArchive   = "archive"
+	RevisionUnarchive = "unarchive"
+	RevisionRestore   = "restore"
+	RevisionDelete    = "delete"
+	RevisionUndelete  = "undelete"
+	RevisionPurge     = "purge"
+)
+
+// ItemRevision is a full snapshot of an item taken every time it is written.
+type ItemRevision struct {
+	ID        int64           `json:"id"`
+	ItemID    int64           `json:"itemId"`
+	Revision  int             `json:"revision"`
+	Action    string          `json:"action"`
+	Snapshot  json.RawMessage `json:"item"`
+	ChangedBy *int64          `json:"changedBy"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// Item decodes the revision's snapshot.
+func (r *ItemRevision) Item() (*Item, error) {
+	var item Item
+
+	err := json.Unmarshal(r.Snapshot, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffItemRevisions lists the fields that differ between two revisions,
+// ordered by field name. updatedAt is left out since it changes on every
+// write.
+func DiffItemRevisions(from, to *ItemRevision) ([]FieldChange, error) {
+	a, err := normaliseSnapshot(from.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := normaliseSnapshot(to.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]bool)
+	for field := range a {
+		fields[field] = true
+	}
+	for field := range b {
+		fields[field] = true
+	}
+	delete(fields, "updatedAt")
+
+	changes := []FieldChange{}
+
+	for field := range fields {
+		if !reflect.DeepEqual(a[field], b[field]) {
+			changes = append(changes, FieldChange{Field: field, From: a[field], To: b[field]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+// normaliseSnapshot decodes a snapshot and encodes it again the way
+// itemSnapshot does, with times in UTC. Snapshots seeded by the migration or
+// written before a field existed then only differ from current ones where the
+// item itself does.
+func normaliseSnapshot(snapshot json.RawMessage) (map[string]any, error) {
+	var item Item
+
+	err := json.Unmarshal(snapshot, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	item.CreatedAt = item.CreatedAt.UTC()
+	item.UpdatedAt = item.UpdatedAt.UTC()
+	if item.DeletedAt != nil {
+		deletedAt := item.DeletedAt.UTC()
+		item.DeletedAt = &deletedAt
+	}
+
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	if item.Attributes == nil {
+		item.Attributes = ItemAttributes{}
+	}
+
+	js, err := itemSnapshot(&item)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+
+	err = json.Unmarshal(js, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// itemSnapshot marshals the stored fields of an item for a revision. Fields
+// computed for responses, such as expansions, the price display and search
+// highlights, are left out so they don't show up in diffs.
+func itemSnapshot(item *Item) ([]byte, error) {
+	snapshot := *item
+	snapshot.PriceDisplay = ""
+	snapshot.SupplierDetails = nil
+	snapshot.ImageURLs = nil
+	snapshot.Highlights = nil
+	snapshot.Stock = nil
+
+	return json.Marshal(snapshot)
+}
+
+// recordItemRevision stores a snapshot of item as its next revision. It must
+// run in the transaction that wrote the item, after the write, so the row lock
+// serialises revision numbers.
+func recordItemRevision(ctx context.Context, tx *sql.Tx, item *Item, action string, userID int64) error {
+	js, err := itemSnapshot(item)
+	if err != nil {
+		return err
+	}
+
+	qry := `
+		INSERT INTO item_revisions (item_id, revision, action, snapshot, changed_by)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, NULLIF($4, 0)
+		FROM item_revisions
+		WHERE item_id = $1`
+
+	_, err = tx.ExecContext(ctx, qry, item.ID, action, js, userID)
+	return err
+}
+
+// recordItemRevisions stores a snapshot of each of the items as its next
+// revision in a single statement, for writes that change many items at once.
+func recordItemRevisions(ctx context.Context, tx *sql.Tx, items []*Item, action string, userID int64) error {
+	ids := make([]int64, len(items))
+	snapshots := make([]string, len(items))
+
+	for i, item := range items {
+		js, err := itemSnapshot(item)
+		if err != nil {
+			return err
+		}
+		ids[i] = item.ID
+		snapshots[i] = string(js)
+	}
+
+	qry := `
+		INSERT INTO item_revisions (item_id, revision, action, snapshot, changed_by)
+		SELECT s.item_id, COALESCE((SELECT MAX(revision) FROM item_revisions WHERE item_revisions.item_id = s.item_id), 0) + 1, $3, s.snapshot::jsonb, NULLIF($4, 0)
+		FROM unnest($1::bigint[], $2::text[]) AS s(item_id, snapshot)`
+
+	_, err := tx.ExecContext(ctx, qry, pq.Array(ids), pq.Array(snapshots), action, userID)
+	return err
+}
+
+type ItemRevisionModel struct {
+	DB *sql.DB
+}
+
+func (m *ItemRevisionModel) Get(itemID, revision int64) (*ItemRevision, error) {
+	qry := `
+		SELECT id, item_id, revision, action, snapshot, changed_by, created_at
+		FROM item_revisions
+		WHERE item_id = $1 AND revision = $2`
+
+	var rev ItemRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, itemID, revision).Scan(
+		&rev.ID,
+		&rev.ItemID,
+		&rev.Revision,
+		&rev.Action,
+		&rev.Snapshot,
+		&rev.ChangedBy,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &rev, nil
+}
+
+// GetLatest returns an item's most recent revision.
+func (m *ItemRevisionModel) GetLatest(itemID int64) (*ItemRevision, error) {
+	qry := `
+		SELECT id, item_id, revision, action, snapshot, changed_by, created_at
+		FROM item_revisions
+		WHERE item_id = $1
+		ORDER BY revision DESC
+		LIMIT 1`
+
+	var rev ItemRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, itemID).Scan(
+		&rev.ID,
+		&rev.ItemID,
+		&rev.Revision,
+		&rev.Action,
+		&rev.Snapshot,
+		&rev.ChangedBy,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	return &rev, nil
+}
+
+func (m *ItemRevisionModel) GetAllForItem(itemID int64, filters Filters) ([]*ItemRevision, Metadata, error) {
+	qry := `
+		SELECT count(*) OVER(), id, item_id, revision, action, snapshot, changed_by, created_at
+		FROM item_revisions
+		WHERE item_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, itemID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*ItemRevision{}
+
+	for rows.Next() {
+		var rev ItemRevision
+		err := rows.Scan(
+			&totalRecords,
+			&rev.ID,
+			&rev.ItemID,
+			&rev.Revision,
+			&rev.Action,
+			&rev.Snapshot,
+			&rev.ChangedBy,
+			&rev.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffItemRevisionsSeededSnapshot(t *testing.T) {
+	// The shape migration 000014 seeds the first revision of existing items
+	// with.
+	seeded := `{"id": 7, "name": "Drill", "model": "D-1", "supplier": 3, "price": "12.50", "currency": "EUR", "image": "", "notes": "", "tags": ["tools"], "createdAt": "2023-04-01T09:30:00+00:00", "updatedAt": "2023-04-01T09:30:00+00:00", "archived": false}`
+
+	// An earlier shape with the price as an object.
+	object := `{"id": 7, "name": "Drill", "model": "D-1", "supplier": 3, "price": {"amount": "12.50", "display": "€12.50"}, "currency": "EUR", "image": "", "notes": "", "tags": ["tools"], "attributes": {}, "createdAt": "2023-04-01T11:30:00+02:00", "updatedAt": "2023-04-02T09:30:00Z", "archived": false}`
+
+	item := &Item{
+		ID:         7,
+		Name:       "Drill",
+		Model:      "D-1",
+		Supplier:   3,
+		Currency:   "EUR",
+		Tags:       []string{"tools"},
+		Attributes: ItemAttributes{},
+	}
+	item.Price.Amount, _ = ParseMoney("12.50")
+	item.PriceDisplay = "€12.50"
+	item.Stock = &StockSummary{Total: 4, Locations: 1}
+
+	err := json.Unmarshal([]byte(`"2023-04-01T09:30:00Z"`), &item.CreatedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.UpdatedAt = item.CreatedAt
+
+	current, err := itemSnapshot(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{name: "seeded to current", from: seeded, to: string(current)},
+		{name: "object price to current", from: object, to: string(current)},
+		{name: "seeded to object price", from: seeded, to: object},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := DiffItemRevisions(&ItemRevision{Snapshot: json.RawMessage(tt.from)}, &ItemRevision{Snapshot: json.RawMessage(tt.to)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(changes) != 0 {
+				t.Errorf("got changes %+v; want none", changes)
+			}
+		})
+	}
+}
+
+func TestDiffItemRevisionsChanges(t *testing.T) {
+	from := `{"id": 7, "name": "Drill", "price": "12.50", "currency": "EUR", "tags": ["tools"], "createdAt": "2023-04-01T09:30:00+00:00", "updatedAt": "2023-04-01T09:30:00+00:00"}`
+	to := `{"id": 7, "name": "Hammer drill", "price": "14.00", "currency": "EUR", "tags": ["tools"], "attributes": {}, "createdAt": "2023-04-01T09:30:00Z", "updatedAt": "2023-05-01T09:30:00Z"}`
+
+	changes, err := DiffItemRevisions(&ItemRevision{Snapshot: json.RawMessage(from)}, &ItemRevision{Snapshot: json.RawMessage(to)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FieldChange{
+		{Field: "name", From: "Drill", To: "Hammer drill"},
+		{Field: "price", From: "12.50", To: "14.00"},
+	}
+
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %+v; want %+v", changes, want)
+	}
+
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("got change %+v; want %+v", changes[i], want[i])
+		}
+	}
+}
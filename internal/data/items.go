@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/vmx-pso/item-service/internal/validator"
@@ -13,45 +15,159 @@ import (
 )
 
 type Item struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Model     string    `json:"model"`
-	Supplier  int64     `json:"supplier"`
-	Price     float64   `json:"price"`
-	Currency  int64     `json:"currency"`
-	ImageFile string    `json:"image"`
-	Notes     string    `json:"notes"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Archived  bool      `json:"archived"`
-}
-
-func ValidateItem(v *validator.Validator, item *Item) {
+	ID         int64          `json:"id"`
+	Name       string         `json:"name"`
+	Model      string         `json:"model"`
+	Supplier   int64          `json:"supplier"`
+	Price      Price          `json:"price"`
+	Currency   string         `json:"currency"`
+	ImageFile  string         `json:"image"`
+	Notes      string         `json:"notes"`
+	Tags       []string       `json:"tags"`
+	Category   *int64         `json:"category,omitempty"`
+	Attributes ItemAttributes `json:"attributes"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	Archived   bool           `json:"archived"`
+
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy *int64     `json:"deletedBy,omitempty"`
+
+	PriceDisplay    string            `json:"priceDisplay,omitempty"`
+	SupplierDetails *Supplier         `json:"supplierDetails,omitempty"`
+	Stock           *StockSummary     `json:"stock,omitempty"`
+	ImageURLs       map[string]string `json:"imageUrls,omitempty"`
+	Highlights      *ItemHighlights   `json:"highlights,omitempty"`
+
+	// stored holds the category and attributes the item was last read or
+	// written with, and is nil for items not yet inserted.
+	stored *storedAttributes
+}
+
+// ItemHighlights holds the fields of an item that matched a search, HTML
+// escaped and with the matching words wrapped in <mark> elements. Fields
+// without a match are left empty.
+type ItemHighlights struct {
+	Name  string `json:"name,omitempty"`
+	Model string `json:"model,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+var (
+	ErrUnknownSupplier = errors.New("unknown supplier")
+	ErrUnknownCategory = errors.New("unknown category")
+)
+
+// ValidateItem checks an item before it is stored. It normalises the item's
+// tags by TagRules first, so tags differing only in ways the rules remove
+// count as duplicates. The currency code and price are normalised to the
+// currency's canonical code and minor-unit scale. Custom attributes are
+// checked against schema, which must be the one for the item's category.
+// For an item read from the database, attribute values the write leaves
+// unchanged are not checked again, so changed or deleted definitions don't
+// block unrelated edits.
+func ValidateItem(v *validator.Validator, item *Item, currencies *CurrencyRegistry, schema AttributeSchema) {
 	v.Check(item.Name != "", "name", "must be provided")
 	v.Check(len(item.Name) <= 255, "name", "must not be more than 255 characters long")
 	v.Check(item.Supplier != 0, "supplier", "must be provided")
-	v.Check(item.Price != 0, "price", "must be provided")
-	v.Check(item.Price > 0, "price", "must be a positive value")
-	v.Check(item.Currency != 0, "currency", "must be provided")
+	validatePrice(v, currencies, &item.Price, &item.Currency)
+
+	item.Tags = normalizeTags(item.Tags)
+
+	for _, tag := range item.Tags {
+		ValidateTag(v, "tags", tag)
+	}
 	v.Check(validator.Unique(item.Tags), "tags", "must not contain duplicate values")
+
+	if item.Attributes == nil {
+		item.Attributes = ItemAttributes{}
+	}
+	validateAttributes(v, item.Attributes, item.Category, schema, item.stored)
+}
+
+// priceColumn selects the item price rounded to its currency's minor-unit
+// scale, so 12.5 EUR is read back as "12.50" and 1500 JPY as "1500".
+const priceColumn = `round(price, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = items.currency), 4))`
+
+// validatePrice checks a price against its currency, replacing the currency
+// code with the canonical one and expressing the amount at the currency's
+// minor-unit scale.
+func validatePrice(v *validator.Validator, currencies *CurrencyRegistry, price *Price, currencyCode *string) {
+	v.Check(!price.Amount.IsZero(), "price", "must be provided")
+	v.Check(price.Amount.Sign() > 0, "price", "must be a positive value")
+	v.Check(*currencyCode != "", "currency", "must be provided")
+
+	if *currencyCode != "" {
+		currency, ok := currencies.Lookup(*currencyCode)
+		v.Check(ok, "currency", "must be a supported ISO 4217 currency code")
+
+		if ok {
+			*currencyCode = currency.Code
+
+			if price.thousands && currency.Exponent == 3 {
+				price.Amount = Money{units: price.Amount.units, scale: 3}
+			}
+
+			amount, err := price.Amount.Rescale(currency.Exponent)
+			v.Check(err == nil, "price", fmt.Sprintf("must not have more than %d decimal places for %s", currency.Exponent, currency.Code))
+			if err == nil {
+				price.Amount = amount
+			}
+
+			v.Check(price.matchesCurrency(currency), "price", fmt.Sprintf("currency symbol does not match the item currency %s", currency.Code))
+		}
+	}
 }
 
 type ItemModel struct {
 	DB *sql.DB
 }
 
-func (m *ItemModel) Insert(item *Item) error {
+func (m *ItemModel) Insert(item *Item, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertItem(ctx, tx, item, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertItem(ctx context.Context, tx *sql.Tx, item *Item, userID int64) error {
 	qry := `
-		INSERT INTO items(name, model, supplier, price, currency, image_file, notes, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO items(name, model, supplier, price, currency, image_file, notes, tags, category, attributes, archived)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM currencies WHERE code = $5), $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at`
-	args := []interface{}{item.Name, item.Model, item.Supplier, item.Price, item.Currency, item.ImageFile, item.Notes, pq.Array(item.Tags)}
+	args := []interface{}{item.Name, item.Model, item.Supplier, item.Price.Amount, item.Currency, item.ImageFile, item.Notes, pq.Array(item.Tags), item.Category, item.Attributes, item.Archived}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	err := tx.QueryRowContext(ctx, qry, args...).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "items" violates foreign key constraint "items_supplier_fkey"`:
+			return ErrUnknownSupplier
+		case err.Error() == `pq: insert or update on table "items" violates foreign key constraint "items_category_fkey"`:
+			return ErrUnknownCategory
+		default:
+			return err
+		}
+	}
+
+	item.markStored()
 
-	return m.DB.QueryRowContext(ctx, qry, args...).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	err = recordItemPrice(ctx, tx, item, item.CreatedAt, userID)
+	if err != nil {
+		return err
+	}
+
+	return recordItemRevision(ctx, tx, item, RevisionCreate, userID)
 }
 
 func (m *ItemModel) Get(id int64) (*Item, error) {
@@ -59,30 +175,19 @@ func (m *ItemModel) Get(id int64) (*Item, error) {
 		return nil, ErrNoRecord
 	}
 
-	qry := `
-		SELECT id, name, model, supplier, price, currency, image_file, notes, tags, created_at, updated_at, archived
-		FROM items
-		WHERE id = $1`
-
-	var item Item
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, qry, id).Scan(
-		&item.ID,
-		&item.Name,
-		&item.Model,
-		&item.Supplier,
-		&item.Price,
-		&item.Currency,
-		&item.ImageFile,
-		&item.Notes,
-		pq.Array(&item.Tags),
-		&item.CreatedAt,
-		&item.UpdatedAt,
-		&item.Archived,
-	)
+	return getItem(ctx, m.DB, id)
+}
+
+func getItem(ctx context.Context, q queryer, id int64) (*Item, error) {
+	qry := `
+		SELECT ` + itemListColumns + `
+		FROM items
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	item, err := scanListItem(q.QueryRowContext(ctx, qry, id))
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -91,21 +196,55 @@ func (m *ItemModel) Get(id int64) (*Item, error) {
 			return nil, err
 		}
 	}
-	return &item, nil
+
+	return item, nil
+}
+
+func (m *ItemModel) Update(item *Item, userID int64) error {
+	return m.update(item, RevisionUpdate, userID)
+}
+
+// Restore writes an item whose fields have been set back to those of an
+// earlier revision. It is an ordinary update apart from how the new revision
+// is labelled.
+func (m *ItemModel) Restore(item *Item, userID int64) error {
+	return m.update(item, RevisionRestore, userID)
+}
+
+func (m *ItemModel) update(item *Item, action string, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateItem(ctx, tx, item, action, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m *ItemModel) Update(item *Item) error {
+// updateItem writes item if it hasn't changed since it was read and records
+// the new revision. An update that only archives or unarchives the item is
+// recorded as such rather than as a plain update.
+func updateItem(ctx context.Context, tx *sql.Tx, item *Item, action string, userID int64) error {
 	qry := `
 		UPDATE items
-		SET name = $1, model = $2, supplier = $3, price = $4, currency = $5, image_file = $6, notes = $7, tags = $8, updated_at = $9, archived = $10
-		WHERE id=$11 AND updated_at=$12
-		RETURNING updated_at`
+		SET name = $1, model = $2, supplier = $3, price = $4, currency = (SELECT id FROM currencies WHERE code = $5), image_file = $6, notes = $7, tags = $8, updated_at = $9, archived = $10, category = $13, attributes = $14
+		FROM (SELECT archived FROM items WHERE id = $11) AS old
+		WHERE items.id = $11 AND items.updated_at = $12 AND items.deleted_at IS NULL
+		RETURNING items.updated_at, old.archived`
 
 	args := []interface{}{
 		item.Name,
 		item.Model,
 		item.Supplier,
-		item.Price,
+		item.Price.Amount,
 		item.Currency,
 		item.ImageFile,
 		item.Notes,
@@ -114,14 +253,19 @@ func (m *ItemModel) Update(item *Item) error {
 		item.Archived,
 		item.ID,
 		item.UpdatedAt,
+		item.Category,
+		item.Attributes,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	var wasArchived bool
 
-	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&item.UpdatedAt)
+	err := tx.QueryRowContext(ctx, qry, args...).Scan(&item.UpdatedAt, &wasArchived)
 	if err != nil {
 		switch {
+		case err.Error() == `pq: insert or update on table "items" violates foreign key constraint "items_supplier_fkey"`:
+			return ErrUnknownSupplier
+		case err.Error() == `pq: insert or update on table "items" violates foreign key constraint "items_category_fkey"`:
+			return ErrUnknownCategory
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
@@ -129,82 +273,756 @@ func (m *ItemModel) Update(item *Item) error {
 		}
 	}
 
-	return nil
+	item.markStored()
+
+	err = recordItemPrice(ctx, tx, item, item.UpdatedAt, userID)
+	if err != nil {
+		return err
+	}
+
+	if action == RevisionUpdate && item.Archived != wasArchived {
+		action = RevisionArchive
+		if !item.Archived {
+			action = RevisionUnarchive
+		}
+	}
+
+	return recordItemRevision(ctx, tx, item, action, userID)
+}
+
+// trashedItemColumns are the columns returned when an item is moved into,
+// restored from or purged from the trash, in the order scanTrashedItem reads
+// them.
+var trashedItemColumns = fmt.Sprintf(`id, name, model, supplier, %s, COALESCE((SELECT code FROM currencies WHERE currencies.id = items.currency), ''), image_file, notes, tags, category, attributes, created_at, updated_at, archived, deleted_at, deleted_by`, priceColumn)
+
+// scanFunc lets a function stand in for a row, so extra leading columns such
+// as a window count can be scanned alongside a shared column list.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
+
+func scanTrashedItem(row interface{ Scan(...any) error }) (*Item, error) {
+	var item Item
+
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Model,
+		&item.Supplier,
+		&item.Price.Amount,
+		&item.Currency,
+		&item.ImageFile,
+		&item.Notes,
+		pq.Array(&item.Tags),
+		&item.Category,
+		&item.Attributes,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.Archived,
+		&item.DeletedAt,
+		&item.DeletedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
 }
 
-func (m *ItemModel) Delete(id int64) error {
+// Delete moves an item into the trash. It disappears from every other query
+// but can be restored until it is purged.
+func (m *ItemModel) Delete(id, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteItem(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteItem(ctx context.Context, tx *sql.Tx, id, userID int64) error {
 	if id < 1 {
 		return ErrNoRecord
 	}
 
+	qry := `
+		UPDATE items
+		SET deleted_at = NOW(), deleted_by = NULLIF($2, 0)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + trashedItemColumns
+
+	_, err := writeTrash(ctx, tx, qry, RevisionDelete, userID, id, userID)
+	return err
+}
+
+// Undelete restores an item from the trash.
+func (m *ItemModel) Undelete(id, userID int64) (*Item, error) {
+	qry := `
+		UPDATE items
+		SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + trashedItemColumns
+
+	return m.changeTrash(qry, RevisionUndelete, id, userID)
+}
+
+// Purge permanently deletes an item from the trash along with its
+// attachments and price history; only its revisions are kept. It returns the
+// storage keys of the item's attachments so the caller can remove the files.
+func (m *ItemModel) Purge(id, userID int64) ([]string, error) {
+	keys, err := m.attachmentKeys(id)
+	if err != nil {
+		return nil, err
+	}
+
 	qry := `
 		DELETE FROM items
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + trashedItemColumns
+
+	_, err = m.changeTrash(qry, RevisionPurge, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// PurgeExpired purges up to 100 items that were moved into the trash before
+// the given time, returning the storage keys of their attachments.
+func (m *ItemModel) PurgeExpired(before time.Time) (int, []string, error) {
+	qry := `
+		SELECT id
+		FROM items
+		WHERE deleted_at < $1
+		ORDER BY deleted_at ASC
+		LIMIT 100`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, qry, id)
+	rows, err := m.DB.QueryContext(ctx, qry, before)
 	if err != nil {
-		return err
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	purged := 0
+	var keys []string
+
+	for _, id := range ids {
+		itemKeys, err := m.Purge(id, 0)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNoRecord):
+				continue
+			default:
+				return purged, keys, err
+			}
+		}
+		purged++
+		keys = append(keys, itemKeys...)
+	}
+
+	return purged, keys, nil
+}
+
+// changeTrash runs a statement that takes an item's id as its only argument
+// and moves it out of the trash, recording the change as a revision.
+func (m *ItemModel) changeTrash(qry, action string, id, userID int64) (*Item, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return ErrNoRecord
+	item, err := writeTrash(ctx, tx, qry, action, userID, id)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return item, tx.Commit()
 }
 
-func (m *ItemModel) GetAll(name string, supplier int, tags []string, filters Filters) ([]*Item, Metadata, error) {
-	qry := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, name, model, supplier, price, currency, notes, tags, created_at, updated_at, archived
+// writeTrash runs a statement that moves an item into or out of the trash and
+// records the item as it was returned by the statement as a new revision.
+func writeTrash(ctx context.Context, tx *sql.Tx, qry, action string, userID int64, args ...any) (*Item, error) {
+	item, err := scanTrashedItem(tx.QueryRowContext(ctx, qry, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+
+	err = recordItemRevision(ctx, tx, item, action, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (m *ItemModel) attachmentKeys(id int64) ([]string, error) {
+	qry := `
+		SELECT storage_key
+		FROM item_attachments
+		WHERE item_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetTrash lists the items in the trash, most recently deleted first.
+func (m *ItemModel) GetTrash(filters Filters) ([]*Item, Metadata, error) {
+	qry := `
+		SELECT count(*) OVER(), ` + trashedItemColumns + `
 		FROM items
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	items := []*Item{}
+
+	for rows.Next() {
+		item, err := scanTrashedItem(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&totalRecords}, dest...)...)
+		}))
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return items, metadata, nil
+}
+
+// ItemQuery holds the conditions items are listed by. Zero values match
+// every item.
+type ItemQuery struct {
+	IDs          []int64
+	Name         string
+	Supplier     int
+	SupplierName string
+	// Model matches models starting with it, ignoring case.
+	Model string
+	// Tags matches items carrying all of them, AnyTags items carrying at
+	// least one.
+	Tags    []string
+	AnyTags []string
+	// Archived limits the results to archived or unarchived items when set.
+	Archived *bool
+	Currency string
+	PriceMin *Money
+	PriceMax *Money
+	// CreatedAfter and UpdatedSince are inclusive.
+	CreatedAfter time.Time
+	UpdatedSince time.Time
+	// Filter is an expression from ParseItemFilter that items must also
+	// match.
+	Filter *FilterExpr
+	// Search is a web search style query matched against the name, model,
+	// tags and notes, parsed with the text search configuration Language.
+	Search   string
+	Language string
+	// Fuzzy matches the search against names and models by trigram
+	// similarity instead, which tolerates misspellings.
+	Fuzzy bool
+	// Category limits the results to items in that category, or anywhere in
+	// its subtree with IncludeDescendants.
+	Category           int
+	IncludeDescendants bool
+}
+
+// SearchLanguages are the text search configurations a search can be parsed
+// with. The items.search column holds lexemes for each of them, so adding one
+// needs a migration.
+var SearchLanguages = []string{"simple", "english"}
+
+// itemRelevance ranks an item against the search in ItemQuery.args. Name
+// matches weigh most, then model, tags and notes. Fuzzy searches rank by
+// trigram similarity instead.
+const (
+	itemRelevance      = `ts_rank_cd(search, websearch_to_tsquery($14::regconfig, $15))`
+	itemFuzzyRelevance = `greatest(word_similarity($15, name), word_similarity($15, model))`
+)
+
+// fuzzySimilarityThreshold is the word similarity a name or model needs to
+// match a fuzzy search. pg_trgm's default of 0.6 misses most single typos in
+// short words.
+const fuzzySimilarityThreshold = 0.4
+
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// itemHighlightColumns select the name, model and notes of an item with the
+// words matching the search marked, or empty strings without a search.
+func itemHighlightColumns(query ItemQuery) string {
+	if query.Search == "" {
+		return `'', '', ''`
+	}
+
+	headline := func(column, options string) string {
+		return fmt.Sprintf(`ts_headline($14::regconfig, COALESCE(%s, ''), websearch_to_tsquery($14::regconfig, $15), 'StartSel=%s, StopSel=%s, %s')`, column, highlightStart, highlightStop, options)
+	}
+
+	return headline("name", "HighlightAll=true") + ", " +
+		headline("model", "HighlightAll=true") + ", " +
+		headline("notes", "MaxFragments=2, MaxWords=20, MinWords=5")
+}
+
+// newItemHighlights turns headlines selected by itemHighlightColumns into
+// ItemHighlights, or nil when none of them matched.
+func newItemHighlights(name, model, notes string) *ItemHighlights {
+	format := func(headline string) string {
+		if !strings.Contains(headline, highlightStart) {
+			return ""
+		}
+		return highlightReplacer.Replace(html.EscapeString(headline))
+	}
+
+	h := ItemHighlights{Name: format(name), Model: format(model), Notes: format(notes)}
+	if h == (ItemHighlights{}) {
+		return nil
+	}
+
+	return &h
+}
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// itemOrder returns the expression and direction items are sorted by.
+// Sorting by relevance puts the best matches for the search first.
+func itemOrder(query ItemQuery, filters Filters) (string, string) {
+	column := filters.sortColumn()
+	if column == "relevance" {
+		if query.Fuzzy {
+			return itemFuzzyRelevance, "DESC"
+		}
+		return itemRelevance, "DESC"
+	}
+
+	if strings.HasPrefix(column, itemAttributeSortPrefix) {
+		key := strings.TrimPrefix(column, itemAttributeSortPrefix)
+		if !AttributeNameRX.MatchString(key) {
+			panic("unsafe attribute sort: " + key)
+		}
+		// Items without the attribute get an empty object, which sorts after
+		// every scalar, so they come last in ascending order as NULLs would,
+		// while keeping the value usable in a keyset cursor.
+		return fmt.Sprintf("COALESCE(attributes->'%s', '{}'::jsonb)", key), filters.sortDirection()
+	}
+
+	return column, filters.sortDirection()
+}
+
+// itemFilterFields are the fields item filter expressions can compare.
+var itemFilterFields = map[string]filterField{
+	"id":            {"id", filterInt},
+	"name":          {"name", filterText},
+	"model":         {"model", filterText},
+	"notes":         {"COALESCE(notes, '')", filterText},
+	"supplier":      {"supplier", filterInt},
+	"supplier_name": {"(SELECT name FROM suppliers WHERE suppliers.id = items.supplier)", filterText},
+	"price":         {"price", filterMoney},
+	"currency":      {"(SELECT code FROM currencies WHERE currencies.id = items.currency)", filterCode},
+	"tags":          {"COALESCE(tags, '{}')", filterTags},
+	"category":      {"category", filterInt},
+	"attributes":    {"attributes", filterAttributes},
+	"archived":      {"archived", filterBool},
+	"created_at":    {"created_at", filterTime},
+	"updated_at":    {"updated_at", filterTime},
+}
+
+// ParseItemFilter parses a filter expression over the item fields named in
+// safelist. Errors are *FilterError values giving the position at fault.
+// Prices can only be compared alongside currency = <code> unless
+// currencyFixed reports that the query already restricts the currency.
+func ParseItemFilter(expr string, safelist []string, currencyFixed bool) (*FilterExpr, error) {
+	fields := make(map[string]filterField, len(safelist))
+
+	for _, name := range safelist {
+		field, ok := itemFilterFields[name]
+		if !ok {
+			panic("unknown item filter field: " + name)
+		}
+		fields[name] = field
+	}
+
+	filter, err := parseFilter(expr, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAmountCurrency(filter.root, "currency", currencyFixed, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// itemQueryConditions filters items by the arguments returned from
+// ItemQuery.args, which must come first in a query's argument list.
+const itemQueryConditions = `
 		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND (supplier = $2 OR $2 = 0)
-		AND (tags @> $3 OR $3 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+		AND (supplier IN (SELECT id FROM suppliers WHERE suppliers.name = $3) OR $3 = '')
+		AND (tags @> $4 OR $4 = '{}')
+		AND (archived = $5 OR $5 IS NULL)
+		AND (id = ANY($6) OR $6 = '{}')
+		AND (lower(model) LIKE $7 OR $7 = '')
+		AND (tags && $8 OR $8 = '{}')
+		AND (currency = (SELECT id FROM currencies WHERE code = $9) OR $9 = '')
+		AND (price >= $10 OR $10 IS NULL)
+		AND (price <= $11 OR $11 IS NULL)
+		AND (created_at >= $12 OR $12 IS NULL)
+		AND (updated_at >= $13 OR $13 IS NULL)
+		AND (search @@ websearch_to_tsquery($14::regconfig, $15) OR $15 = '' OR $16)
+		AND (NOT $16 OR $15 <% name OR $15 <% model)
+		AND ($17 = 0 OR category = $17 OR ($18 AND category IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE parent_id = $17
+				UNION ALL
+				SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+			)
+			SELECT id FROM subtree
+		)))
+		AND deleted_at IS NULL`
+
+// likeEscaper escapes the LIKE wildcards in a value matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// conditions returns the WHERE clause selecting the items matched by q and
+// the arguments it refers to.
+func (q ItemQuery) conditions() (string, []any) {
+	conditions := itemQueryConditions
+	args := q.args()
+
+	if q.Filter != nil {
+		conditions += "\n\t\tAND " + q.Filter.sql(&args)
+	}
+
+	return conditions, args
+}
+
+func (q ItemQuery) args() []any {
+	var modelPrefix string
+	language := q.Language
+	if language == "" {
+		language = "simple"
+	}
+	var priceMin, priceMax, createdAfter, updatedSince any
+
+	if q.Model != "" {
+		modelPrefix = likeEscaper.Replace(strings.ToLower(q.Model)) + "%"
+	}
+
+	if q.PriceMin != nil {
+		priceMin = *q.PriceMin
+	}
+
+	if q.PriceMax != nil {
+		priceMax = *q.PriceMax
+	}
+
+	if !q.CreatedAfter.IsZero() {
+		createdAfter = q.CreatedAfter
+	}
+
+	if !q.UpdatedSince.IsZero() {
+		updatedSince = q.UpdatedSince
+	}
+
+	return []any{
+		q.Name,
+		q.Supplier,
+		q.SupplierName,
+		pq.Array(q.Tags),
+		q.Archived,
+		pq.Array(q.IDs),
+		modelPrefix,
+		pq.Array(q.AnyTags),
+		q.Currency,
+		priceMin,
+		priceMax,
+		createdAfter,
+		updatedSince,
+		language,
+		q.Search,
+		q.Fuzzy && q.Search != "",
+		q.Category,
+		q.IncludeDescendants,
+	}
+}
+
+// itemListColumns are the columns item listings select, in the order
+// scanListItem reads them.
+var itemListColumns = fmt.Sprintf(`id, name, model, supplier, %s, COALESCE((SELECT code FROM currencies WHERE currencies.id = items.currency), ''), image_file, notes, tags, category, attributes, created_at, updated_at, archived`, priceColumn)
+
+func scanListItem(row interface{ Scan(...any) error }) (*Item, error) {
+	var item Item
+
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Model,
+		&item.Supplier,
+		&item.Price.Amount,
+		&item.Currency,
+		&item.ImageFile,
+		&item.Notes,
+		pq.Array(&item.Tags),
+		&item.Category,
+		&item.Attributes,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.Archived,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.markStored()
+
+	return &item, nil
+}
+
+// queryItems runs a query listing the items matching query. Fuzzy searches
+// run in a read-only transaction so the similarity threshold can be lowered
+// for them alone. The returned function releases the rows and must be called
+// once they have been read.
+func (m *ItemModel) queryItems(ctx context.Context, query ItemQuery, qry string, args ...any) (*sql.Rows, func(), error) {
+	if !query.Fuzzy {
+		rows, err := m.DB.QueryContext(ctx, qry, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() { rows.Close() }, nil
+	}
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", fuzzySimilarityThreshold))
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, qry, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return rows, func() {
+		rows.Close()
+		tx.Rollback()
+	}, nil
+}
+
+// Matches reports whether any item matches query.
+func (m *ItemModel) Matches(query ItemQuery) (bool, error) {
+	conditions, args := query.conditions()
+
+	qry := `SELECT EXISTS (SELECT 1 FROM items ` + conditions + `)`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{name, supplier, pq.Array(tags), filters.limit(), filters.offset()}
+	var exists bool
 
-	rows, err := m.DB.QueryContext(ctx, qry, args...)
+	rows, done, err := m.queryItems(ctx, query, qry, args...)
 	if err != nil {
-		return nil, Metadata{}, err
+		return false, err
+	}
+	defer done()
+
+	for rows.Next() {
+		err = rows.Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return exists, rows.Err()
+}
+
+type ItemSuggestion struct {
+	Text  string `json:"text"`
+	Field string `json:"field"`
+}
+
+// Suggest returns up to limit distinct names and models of unarchived items
+// completing prefix, those starting with it first and then those most similar
+// to it.
+func (m *ItemModel) Suggest(prefix string, limit int) ([]*ItemSuggestion, error) {
+	qry := `
+		SELECT text, field
+		FROM (
+			SELECT name AS text, 'name' AS field, lower(name) LIKE $2 AS starts, word_similarity($1, name) AS score
+			FROM items
+			WHERE (lower(name) LIKE $2 OR $1 <% name) AND NOT archived AND deleted_at IS NULL
+			UNION
+			SELECT model, 'model', lower(model) LIKE $2, word_similarity($1, model)
+			FROM items
+			WHERE (lower(model) LIKE $2 OR $1 <% model) AND NOT archived AND deleted_at IS NULL
+		) AS matches
+		ORDER BY starts DESC, score DESC, text ASC
+		LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, prefix, likeEscaper.Replace(strings.ToLower(prefix))+"%", limit)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
+	suggestions := []*ItemSuggestion{}
+
+	for rows.Next() {
+		var suggestion ItemSuggestion
+
+		err := rows.Scan(&suggestion.Text, &suggestion.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// GetAll returns a page of the items matching query. When filters carries a
+// cursor the page is read by keyset instead of by offset; see getAllAfter.
+func (m *ItemModel) GetAll(query ItemQuery, filters Filters) ([]*Item, Metadata, error) {
+	if filters.Cursor != nil {
+		return m.getAllAfter(query, filters)
+	}
+
+	conditions, args := query.conditions()
+	args = append(args, filters.limit(), filters.offset())
+
+	order, direction := itemOrder(query, filters)
+
+	qry := fmt.Sprintf(`
+		SELECT count(*) OVER(), %s, %s
+		FROM items
+		%s
+		ORDER BY %s %s, id ASC
+		LIMIT $%d OFFSET $%d`, itemHighlightColumns(query), itemListColumns, conditions, order, direction, len(args)-1, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, done, err := m.queryItems(ctx, query, qry, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer done()
+
 	totalRecords := 0
+	var name, model, notes string
 	items := []*Item{}
 
 	for rows.Next() {
-		var item Item
-		err := rows.Scan(
-			&totalRecords,
-			&item.ID,
-			&item.Name,
-			&item.Model,
-			&item.Supplier,
-			&item.Price,
-			&item.Currency,
-			&item.Notes,
-			pq.Array(&item.Tags),
-			&item.CreatedAt,
-			&item.UpdatedAt,
-			&item.Archived,
-		)
+		item, err := scanListItem(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&totalRecords, &name, &model, &notes}, dest...)...)
+		}))
 		if err != nil {
 			return nil, Metadata{}, err
 		}
-		items = append(items, &item)
+
+		item.Highlights = newItemHighlights(name, model, notes)
+
+		items = append(items, item)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -215,3 +1033,111 @@ func (m *ItemModel) GetAll(name string, supplier int, tags []string, filters Fil
 
 	return items, metadata, nil
 }
+
+// getAllAfter returns the page of items that follows filters.Cursor. Rows are
+// found by comparing the sort column and id with the cursor's, so pages stay
+// cheap however deep they go and items added or removed meanwhile don't
+// shift later pages. No total is counted; the metadata holds the cursor for
+// the next page, which is empty on the last one.
+func (m *ItemModel) getAllAfter(query ItemQuery, filters Filters) ([]*Item, Metadata, error) {
+	conditions, args := query.conditions()
+	order, direction := itemOrder(query, filters)
+
+	after := ""
+	if filters.Cursor.ID > 0 {
+		after = keysetCondition(order, direction, len(args)+1, len(args)+2)
+		args = append(args, filters.Cursor.Value, filters.Cursor.ID)
+	}
+
+	// One row more than the page is read to tell whether there is a next page.
+	args = append(args, filters.limit()+1)
+
+	qry := fmt.Sprintf(`
+		SELECT (%s)::text, %s, %s
+		FROM items
+		%s
+		%s
+		ORDER BY %s %s, id ASC
+		LIMIT $%d`, order, itemHighlightColumns(query), itemListColumns, conditions, after, order, direction, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, done, err := m.queryItems(ctx, query, qry, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer done()
+
+	var sortValue, lastSortValue string
+	var name, model, notes string
+	more := false
+	items := []*Item{}
+
+	for rows.Next() {
+		if len(items) == filters.limit() {
+			more = true
+			break
+		}
+
+		item, err := scanListItem(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&sortValue, &name, &model, &notes}, dest...)...)
+		}))
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		item.Highlights = newItemHighlights(name, model, notes)
+
+		items = append(items, item)
+		lastSortValue = sortValue
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := Metadata{PageSize: filters.PageSize}
+
+	if more {
+		next := Cursor{Sort: filters.Sort, Value: lastSortValue, ID: items[len(items)-1].ID}
+		metadata.NextCursor = next.Encode()
+	}
+
+	return items, metadata, nil
+}
+
+// Stream calls fn for every item matching query, in the order given by the
+// filters' sort; paging is ignored. Rows are read from the connection as fn
+// consumes them, so memory use doesn't grow with the number of items. The
+// query stops when ctx is cancelled or fn returns an error.
+func (m *ItemModel) Stream(ctx context.Context, query ItemQuery, filters Filters, fn func(*Item) error) error {
+	conditions, args := query.conditions()
+	order, direction := itemOrder(query, filters)
+
+	qry := fmt.Sprintf(`
+		SELECT %s
+		FROM items
+		%s
+		ORDER BY %s %s, id ASC`, itemListColumns, conditions, order, direction)
+
+	rows, done, err := m.queryItems(ctx, query, qry, args...)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	for rows.Next() {
+		item, err := scanListItem(rows)
+		if err != nil {
+			return err
+		}
+
+		err = fn(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
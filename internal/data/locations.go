@@ -0,0 +1,334 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+var (
+	ErrDuplicateLocationName = errors.New("duplicate location name")
+	ErrUnknownLocation       = errors.New("unknown location")
+	ErrLocationCycle         = errors.New("location can't be moved into its own subtree")
+	ErrLocationInUse         = errors.New("location has sublocations or stock")
+	ErrLocationLargerParent  = errors.New("location is of a larger kind than its parent")
+	ErrLocationSmallerChild  = errors.New("location is of a smaller kind than one of its sublocations")
+)
+
+// LocationKinds are the kinds of place stock can be kept in, from the
+// largest to the smallest.
+var LocationKinds = []string{"warehouse", "room", "bin"}
+
+// locationKindFits reports whether a location of kind can be kept in one of
+// parentKind, which is when it isn't of a larger kind.
+func locationKindFits(kind, parentKind string) bool {
+	return locationKindRank(kind) >= locationKindRank(parentKind)
+}
+
+// locationKindRank returns the index of kind in LocationKinds.
+func locationKindRank(kind string) int {
+	for i, k := range LocationKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+// Location is a node of the location tree, such as a bin in a room of a
+// warehouse. Parent is nil for top level locations.
+type Location struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Kind      string      `json:"kind"`
+	Parent    *int64      `json:"parent"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Version   int         `json:"version"`
+	Children  []*Location `json:"children,omitempty"`
+}
+
+func ValidateLocation(v *validator.Validator, location *Location) {
+	v.Check(location.Name != "", "name", "must be provided")
+	v.Check(len(location.Name) <= 100, "name", "must not be more than 100 characters long")
+	v.Check(validator.PermittedValue(location.Kind, LocationKinds...), "kind", "must be warehouse, room or bin")
+	v.Check(location.Parent == nil || *location.Parent != location.ID, "parent", "must not be the location itself")
+}
+
+type LocationModel struct {
+	DB *sql.DB
+}
+
+func locationError(err error) error {
+	switch {
+	case err.Error() == `pq: duplicate key value violates unique constraint "locations_parent_name_key"`:
+		return ErrDuplicateLocationName
+	case err.Error() == `pq: insert or update on table "locations" violates foreign key constraint "locations_parent_id_fkey"`:
+		return ErrUnknownLocation
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNoRecord
+	default:
+		return err
+	}
+}
+
+func scanLocation(row interface{ Scan(...any) error }) (*Location, error) {
+	var location Location
+
+	err := row.Scan(
+		&location.ID,
+		&location.Name,
+		&location.Kind,
+		&location.Parent,
+		&location.CreatedAt,
+		&location.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// Insert adds a location. A location can't be of a larger kind than its
+// parent, so a warehouse can't go in a room.
+func (m *LocationModel) Insert(location *Location) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if location.Parent != nil {
+		// Locking the parent stops its kind changing before the insert.
+		var parentKind string
+
+		err = tx.QueryRowContext(ctx, `SELECT kind FROM locations WHERE id = $1 FOR SHARE`, *location.Parent).Scan(&parentKind)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrUnknownLocation
+			default:
+				return err
+			}
+		}
+
+		if !locationKindFits(location.Kind, parentKind) {
+			return ErrLocationLargerParent
+		}
+	}
+
+	qry := `
+		INSERT INTO locations (name, kind, parent_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, qry, location.Name, location.Kind, location.Parent).Scan(&location.ID, &location.CreatedAt, &location.Version)
+	if err != nil {
+		return locationError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (m *LocationModel) Get(id int64) (*Location, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
+	}
+
+	qry := `
+		SELECT id, name, kind, parent_id, created_at, version
+		FROM locations
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	location, err := scanLocation(m.DB.QueryRowContext(ctx, qry, id))
+	if err != nil {
+		return nil, locationError(err)
+	}
+	return location, nil
+}
+
+// GetTree returns the top level locations with their sublocations nested in
+// Children, each level in name order.
+func (m *LocationModel) GetTree() ([]*Location, error) {
+	qry := `
+		SELECT id, name, kind, parent_id, created_at, version
+		FROM locations
+		ORDER BY lower(name) ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []*Location
+	byID := map[int64]*Location{}
+
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+		byID[location.ID] = location
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots := []*Location{}
+
+	for _, location := range locations {
+		if location.Parent == nil {
+			roots = append(roots, location)
+			continue
+		}
+		parent := byID[*location.Parent]
+		parent.Children = append(parent.Children, location)
+	}
+
+	return roots, nil
+}
+
+// Update writes a location's name, kind and parent. A new parent must not be
+// the location itself or one of its sublocations. As with Insert, the
+// location can't be of a larger kind than its parent, nor of a smaller kind
+// than any of its sublocations.
+func (m *LocationModel) Update(location *Location) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// As with categories, concurrent moves could otherwise each pass the
+	// cycle check and together link two subtrees into a loop. The lock also
+	// keeps the kinds checked below from changing.
+	_, err = tx.ExecContext(ctx, `LOCK TABLE locations IN SHARE ROW EXCLUSIVE MODE`)
+	if err != nil {
+		return err
+	}
+
+	if location.Parent != nil {
+		qry := `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM locations WHERE id = $1
+				UNION ALL
+				SELECT locations.id FROM locations JOIN subtree ON locations.parent_id = subtree.id
+			)
+			SELECT COALESCE((SELECT kind FROM locations WHERE id = $2), ''), EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+		var parentKind string
+		var cycle bool
+
+		err = tx.QueryRowContext(ctx, qry, location.ID, *location.Parent).Scan(&parentKind, &cycle)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case parentKind == "":
+			return ErrUnknownLocation
+		case cycle:
+			return ErrLocationCycle
+		case !locationKindFits(location.Kind, parentKind):
+			return ErrLocationLargerParent
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT kind FROM locations WHERE parent_id = $1`, location.ID)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var childKind string
+		err := rows.Scan(&childKind)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		if !locationKindFits(childKind, location.Kind) {
+			rows.Close()
+			return ErrLocationSmallerChild
+		}
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	qry := `
+		UPDATE locations
+		SET name = $1, kind = $2, parent_id = $3, version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING version`
+
+	args := []any{location.Name, location.Kind, location.Parent, location.ID, location.Version}
+
+	err = tx.QueryRowContext(ctx, qry, args...).Scan(&location.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return locationError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a location that has no sublocations and no stock.
+func (m *LocationModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrNoRecord
+	}
+
+	qry := `
+		DELETE FROM locations
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: update or delete on table "locations" violates foreign key constraint "locations_parent_id_fkey" on table "locations"`,
+			err.Error() == `pq: update or delete on table "locations" violates foreign key constraint "item_stock_location_id_fkey" on table "item_stock"`:
+			return ErrLocationInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,18 +11,48 @@ var (
 	ErrEditConflict = errors.New("edit conflict")
 )
 
+// queryer is satisfied by both *sql.DB and *sql.Tx, so helpers that take one
+// can run either on their own or as part of a larger transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Models struct {
-	Items       ItemModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
+	Items            ItemModel
+	Users            UserModel
+	Tokens           TokenModel
+	Permissions      PermissionModel
+	Suppliers        SupplierModel
+	Currencies       CurrencyModel
+	ItemPrices       ItemPriceModel
+	ItemPriceChanges ItemPriceChangeModel
+	ItemAttachments  ItemAttachmentModel
+	ItemRevisions    ItemRevisionModel
+	Tags             TagModel
+	Categories       CategoryModel
+	Attributes       AttributeModel
+	Locations        LocationModel
+	Stock            StockModel
 }
 
 func NewModels(db *sql.DB) *Models {
 	return &Models{
-		Items:       ItemModel{DB: db},
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
+		Items:            ItemModel{DB: db},
+		Users:            UserModel{DB: db},
+		Tokens:           TokenModel{DB: db},
+		Permissions:      PermissionModel{DB: db},
+		Suppliers:        SupplierModel{DB: db},
+		Currencies:       CurrencyModel{DB: db, Registry: &CurrencyRegistry{}},
+		ItemPrices:       ItemPriceModel{DB: db},
+		ItemPriceChanges: ItemPriceChangeModel{DB: db},
+		ItemAttachments:  ItemAttachmentModel{DB: db},
+		ItemRevisions:    ItemRevisionModel{DB: db},
+		Tags:             TagModel{DB: db},
+		Categories:       CategoryModel{DB: db},
+		Attributes:       AttributeModel{DB: db},
+		Locations:        LocationModel{DB: db},
+		Stock:            StockModel{DB: db},
 	}
 }
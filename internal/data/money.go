@@ -0,0 +1,216 @@
+package data
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMoney    = errors.New("invalid money amount")
+	ErrMoneyOutOfRange = errors.New("money amount out of range")
+	ErrMoneyPrecision  = errors.New("money amount has too many decimal places")
+)
+
+const maxMoneyScale = 18
+
+var powersOfTen = [maxMoneyScale + 1]int64{
+	1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000, 10000000000, 100000000000, 1000000000000, 10000000000000, 100000000000000, 1000000000000000, 10000000000000000, 100000000000000000, 1000000000000000000,
+}
+
+// Money is an exact decimal amount held as an integer number of 10^-scale
+// units, so 12.50 is stored as 1250 with a scale of 2. It is written to and
+// read from PostgreSQL numeric columns as text, never as a float.
+type Money struct {
+	units int64
+	scale int
+}
+
+func ParseMoney(s string) (Money, error) {
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasPoint := strings.Cut(s, ".")
+	if intPart == "" || (hasPoint && fracPart == "") {
+		return Money{}, ErrInvalidMoney
+	}
+
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return Money{}, ErrInvalidMoney
+	}
+
+	if len(fracPart) > maxMoneyScale {
+		return Money{}, ErrMoneyPrecision
+	}
+
+	units, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, ErrMoneyOutOfRange
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Money{units: units, scale: len(fracPart)}, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Scale() int {
+	return m.scale
+}
+
+func (m Money) Sign() int {
+	switch {
+	case m.units < 0:
+		return -1
+	case m.units > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+// Cmp compares m with n and returns -1, 0 or +1.
+func (m Money) Cmp(n Money) int {
+	if m.scale < n.scale {
+		return -n.Cmp(m)
+	}
+
+	r, err := n.Rescale(m.scale)
+	if err != nil {
+		// n is too large to express at m's scale, so it is further from zero.
+		return -n.Sign()
+	}
+
+	switch {
+	case m.units < r.units:
+		return -1
+	case m.units > r.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Rescale returns the same amount expressed with the given number of decimal
+// places. It fails with ErrMoneyPrecision rather than rounding when digits
+// would be lost.
+func (m Money) Rescale(scale int) (Money, error) {
+	if scale < 0 || scale > maxMoneyScale {
+		return Money{}, ErrMoneyPrecision
+	}
+
+	switch {
+	case scale == m.scale:
+		return m, nil
+	case scale > m.scale:
+		p := powersOfTen[scale-m.scale]
+		if m.units > math.MaxInt64/p || m.units < math.MinInt64/p {
+			return Money{}, ErrMoneyOutOfRange
+		}
+		return Money{units: m.units * p, scale: scale}, nil
+	default:
+		p := powersOfTen[m.scale-scale]
+		if m.units%p != 0 {
+			return Money{}, ErrMoneyPrecision
+		}
+		return Money{units: m.units / p, scale: scale}, nil
+	}
+}
+
+func (m Money) String() string {
+	u := uint64(m.units)
+	sign := ""
+	if m.units < 0 {
+		u = -u
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(u, 10)
+	if m.scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= m.scale {
+		digits = strings.Repeat("0", m.scale-len(digits)+1) + digits
+	}
+
+	point := len(digits) - m.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON accepts either a JSON string or a JSON number. Numbers are
+// parsed from their literal text so no precision is lost on the way in.
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	s := string(jsonValue)
+
+	if bytes.HasPrefix(jsonValue, []byte(`"`)) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return ErrInvalidMoney
+		}
+		s = unquoted
+	}
+
+	money, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = money
+
+	return nil
+}
+
+func (m *Money) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		money, err := ParseMoney(string(src))
+		if err != nil {
+			return err
+		}
+		*m = money
+	case string:
+		money, err := ParseMoney(src)
+		if err != nil {
+			return err
+		}
+		*m = money
+	case int64:
+		*m = Money{units: src}
+	default:
+		return fmt.Errorf("cannot scan %T into data.Money", src)
+	}
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		scale int
+		err   error
+	}{
+		{name: "integer", input: "12", want: "12", scale: 0},
+		{name: "decimal", input: "12.50", want: "12.50", scale: 2},
+		{name: "trailing zeros kept", input: "1.5000", want: "1.5000", scale: 4},
+		{name: "leading zero", input: "0.05", want: "0.05", scale: 2},
+		{name: "plus sign", input: "+3.1", want: "3.1", scale: 1},
+		{name: "negative", input: "-3.1", want: "-3.1", scale: 1},
+		{name: "negative below one", input: "-0.01", want: "-0.01", scale: 2},
+		{name: "zero", input: "0", want: "0", scale: 0},
+		{name: "maximum scale", input: "0.123456789012345678", want: "0.123456789012345678", scale: 18},
+		{name: "largest", input: "9223372036854775807", want: "9223372036854775807", scale: 0},
+		{name: "smallest", input: "-922337203685477580.7", want: "-922337203685477580.7", scale: 1},
+		{name: "empty", input: "", err: ErrInvalidMoney},
+		{name: "sign only", input: "-", err: ErrInvalidMoney},
+		{name: "no integer part", input: ".5", err: ErrInvalidMoney},
+		{name: "no fraction", input: "5.", err: ErrInvalidMoney},
+		{name: "two points", input: "1.2.3", err: ErrInvalidMoney},
+		{name: "letters", input: "12a", err: ErrInvalidMoney},
+		{name: "exponent", input: "1e3", err: ErrInvalidMoney},
+		{name: "grouping", input: "1,000", err: ErrInvalidMoney},
+		{name: "space", input: " 1", err: ErrInvalidMoney},
+		{name: "double sign", input: "--1", err: ErrInvalidMoney},
+		{name: "too many decimals", input: "0.1234567890123456789", err: ErrMoneyPrecision},
+		{name: "overflow", input: "9223372036854775808", err: ErrMoneyOutOfRange},
+		{name: "overflow with fraction", input: "922337203685477580.8", err: ErrMoneyOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMoney(tt.input)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v; want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := m.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+			if m.Scale() != tt.scale {
+				t.Errorf("got scale %d; want %d", m.Scale(), tt.scale)
+			}
+		})
+	}
+}
+
+func TestMoneyRescale(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		scale int
+		want  string
+		err   error
+	}{
+		{name: "same scale", input: "12.50", scale: 2, want: "12.50"},
+		{name: "widen", input: "12.5", scale: 4, want: "12.5000"},
+		{name: "widen integer", input: "1500", scale: 2, want: "1500.00"},
+		{name: "narrow exactly", input: "12.5000", scale: 2, want: "12.50"},
+		{name: "narrow to integer", input: "1500.00", scale: 0, want: "1500"},
+		{name: "negative", input: "-12.50", scale: 1, want: "-12.5"},
+		{name: "no rounding down", input: "12.504", scale: 2, err: ErrMoneyPrecision},
+		{name: "no rounding up", input: "12.505", scale: 2, err: ErrMoneyPrecision},
+		{name: "no rounding negative", input: "-0.5", scale: 0, err: ErrMoneyPrecision},
+		{name: "negative scale", input: "1", scale: -1, err: ErrMoneyPrecision},
+		{name: "scale too large", input: "1", scale: maxMoneyScale + 1, err: ErrMoneyPrecision},
+		{name: "overflow", input: "92233720368547758", scale: 4, err: ErrMoneyOutOfRange},
+		{name: "negative overflow", input: "-92233720368547758", scale: 4, err: ErrMoneyOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMoney(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := m.Rescale(tt.scale)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v; want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{name: "zero", money: Money{}, want: "0"},
+		{name: "zero with scale", money: Money{units: 0, scale: 2}, want: "0.00"},
+		{name: "below one", money: Money{units: 5, scale: 3}, want: "0.005"},
+		{name: "negative below one", money: Money{units: -5, scale: 2}, want: "-0.05"},
+		{name: "whole", money: Money{units: 1250, scale: 2}, want: "12.50"},
+		{name: "minimum", money: Money{units: -9223372036854775808, scale: 0}, want: "-9223372036854775808"},
+		{name: "minimum with scale", money: Money{units: -9223372036854775808, scale: 4}, want: "-922337203685477.5808"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.money.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyCmp(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1", b: "1.00", want: 0},
+		{a: "1.5", b: "1.49", want: 1},
+		{a: "-1.5", b: "-1.49", want: -1},
+		{a: "0.001", b: "0", want: 1},
+		{a: "9223372036854775807", b: "0.01", want: 1},
+		{a: "0.01", b: "9223372036854775807", want: -1},
+		{a: "-9223372036854775807", b: "0.01", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, err := ParseMoney(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseMoney(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := a.Cmp(b); got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want string
+		err  bool
+	}{
+		{name: "bytes", src: []byte("12.5000"), want: "12.5000"},
+		{name: "string", src: "-3.25", want: "-3.25"},
+		{name: "int64", src: int64(42), want: "42"},
+		{name: "malformed bytes", src: []byte("abc"), err: true},
+		{name: "malformed string", src: "1.2.3", err: true},
+		{name: "float", src: 1.5, err: true},
+		{name: "nil", src: nil, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+
+			err := m.Scan(tt.src)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := m.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
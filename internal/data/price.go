@@ -1,34 +1,264 @@
 package data
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"strconv"
-	"unicode/utf8"
+	"strings"
+	"unicode"
 )
 
 var ErrInvalidPriceFormat = errors.New("invalid price format")
 
-type Price float64
+// Price is an amount together with the currency symbol or code it was written
+// with, if any. The currency itself is held alongside it, as on Item.
+//
+// It can be read from a JSON number ("12.5"), a JSON string with an optional
+// leading or trailing currency symbol or ISO code ("€1.234,50", "12.50 USD")
+// or an object of the form {"amount": "12.50", "currency": "EUR"}. It is always
+// written back out as the bare amount; FormatPrice renders it for display.
+type Price struct {
+	Amount Money
+
+	// symbol is the currency symbol or code that accompanied a parsed price,
+	// if any. ValidateItem checks it against the item's currency.
+	symbol string
+
+	// thousands is set when a lone separator followed by three digits, as in
+	// "1,500", was read as digit grouping. For currencies with three decimal
+	// places validatePrice reads it as the decimal point instead.
+	thousands bool
+}
+
+func (p Price) MarshalJSON() ([]byte, error) {
+	return p.Amount.MarshalJSON()
+}
 
 func (p *Price) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+	switch {
+	case bytes.HasPrefix(jsonValue, []byte("{")):
+		var aux struct {
+			Amount   Money  `json:"amount"`
+			Currency string `json:"currency"`
+			Display  string `json:"display"`
+		}
+
+		err := json.Unmarshal(jsonValue, &aux)
+		if err != nil {
+			return ErrInvalidPriceFormat
+		}
+
+		*p = Price{Amount: aux.Amount, symbol: aux.Currency}
+	case bytes.HasPrefix(jsonValue, []byte(`"`)):
+		unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+		if err != nil {
+			return ErrInvalidPriceFormat
+		}
+
+		price, err := ParsePrice(unquotedJSONValue)
+		if err != nil {
+			return err
+		}
+
+		*p = price
+	default:
+		amount, err := ParseMoney(string(jsonValue))
+		if err != nil {
+			return ErrInvalidPriceFormat
+		}
+
+		*p = Price{Amount: amount}
+	}
+
+	return nil
+}
+
+// ParsePrice reads a human formatted price such as "$12.50", "12,50 €",
+// "EUR 1.234,56" or "1 234.5". A currency symbol or ISO code may appear
+// before or after the number but not both. Spaces and apostrophes are always
+// treated as digit grouping. When both '.' and ',' are present the last one is
+// the decimal separator. A separator that appears more than once is grouping
+// and a lone one is the decimal separator, whichever it is, unless exactly
+// three digits follow it. "1,500" and "1.500" are then read as 1500 until the
+// currency is known; see Price.thousands.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(s)
+
+	first := strings.IndexFunc(s, isASCIIDigit)
+	last := strings.LastIndexFunc(s, isASCIIDigit)
+	if first < 0 {
+		return Price{}, ErrInvalidPriceFormat
+	}
+
+	prefix, number, suffix := s[:first], s[first:last+1], s[last+1:]
+
+	negative := false
+	prefix = strings.TrimSpace(prefix)
+	for _, sign := range []string{"-", "+"} {
+		if strings.HasPrefix(prefix, sign) {
+			negative = sign == "-"
+			prefix = strings.TrimSpace(strings.TrimPrefix(prefix, sign))
+		} else if strings.HasSuffix(prefix, sign) {
+			negative = sign == "-"
+			prefix = strings.TrimSpace(strings.TrimSuffix(prefix, sign))
+		}
+	}
+	suffix = strings.TrimSpace(suffix)
+
+	if prefix != "" && suffix != "" {
+		return Price{}, ErrInvalidPriceFormat
+	}
+
+	symbol := prefix + suffix
+	if strings.IndexFunc(symbol, func(r rune) bool { return unicode.IsDigit(r) || unicode.IsSpace(r) }) >= 0 {
+		return Price{}, ErrInvalidPriceFormat
+	}
+
+	normalised, thousands, err := normaliseSeparators(number)
 	if err != nil {
-		return ErrInvalidPriceFormat
+		return Price{}, err
 	}
 
-	price := trimFirstRune(unquotedJSONValue)
+	if negative {
+		normalised = "-" + normalised
+	}
 
-	f, err := strconv.ParseFloat(price, 64)
+	amount, err := ParseMoney(normalised)
 	if err != nil {
-		return ErrInvalidPriceFormat
+		return Price{}, ErrInvalidPriceFormat
 	}
 
-	*p = Price(f)
+	return Price{Amount: amount, symbol: symbol, thousands: thousands}, nil
+}
 
-	return nil
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isGroupingRune(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u202f' || r == '\''
 }
 
-func trimFirstRune(s string) string {
-	_, i := utf8.DecodeRuneInString(s)
-	return s[i:]
+// normaliseSeparators rewrites a number using locale specific grouping and
+// decimal separators into the plain form accepted by ParseMoney. It reports
+// whether a lone separator followed by three digits was read as grouping.
+func normaliseSeparators(number string) (string, bool, error) {
+	var b strings.Builder
+	previousWasDigit := false
+
+	for _, r := range number {
+		switch {
+		case isASCIIDigit(r):
+			previousWasDigit = true
+		case isGroupingRune(r) || r == '.' || r == ',':
+			if !previousWasDigit {
+				return "", false, ErrInvalidPriceFormat
+			}
+			if isGroupingRune(r) {
+				r = ' '
+			}
+			previousWasDigit = false
+		default:
+			return "", false, ErrInvalidPriceFormat
+		}
+		b.WriteRune(r)
+	}
+
+	number = b.String()
+
+	dots, commas := strings.Count(number, "."), strings.Count(number, ",")
+	decimal := ""
+	thousands := false
+
+	switch {
+	case dots > 0 && commas > 0:
+		decimal = "."
+		if strings.LastIndex(number, ",") > strings.LastIndex(number, ".") {
+			decimal = ","
+		}
+	case dots == 1 || commas == 1:
+		separator := "."
+		if commas == 1 {
+			separator = ","
+		}
+
+		if len(number)-strings.Index(number, separator)-1 == 3 {
+			thousands = true
+		} else {
+			decimal = separator
+		}
+	}
+
+	intPart, fracPart, hasDecimal := number, "", false
+	if decimal != "" {
+		intPart, fracPart, hasDecimal = strings.Cut(number, decimal)
+	}
+
+	if strings.ContainsAny(fracPart, " .,") {
+		return "", false, ErrInvalidPriceFormat
+	}
+
+	// Groups after the first must hold three digits, or two for the Indian
+	// lakh style "1,23,456", and the last group always holds three.
+	groups := strings.FieldsFunc(intPart, func(r rune) bool { return r == ' ' || r == '.' || r == ',' })
+	for i := 1; i < len(groups); i++ {
+		if len(groups[i]) != 3 && (len(groups[i]) != 2 || i == len(groups)-1) {
+			return "", false, ErrInvalidPriceFormat
+		}
+	}
+
+	intPart = strings.Join(groups, "")
+
+	if hasDecimal {
+		return intPart + "." + fracPart, false, nil
+	}
+	return intPart, thousands, nil
+}
+
+// matchesCurrency reports whether the symbol supplied with the price, if any,
+// identifies the given currency: its ISO code in any case, or exactly its
+// symbol, so "$" is accepted for USD but not for A$ or R$.
+func (p Price) matchesCurrency(currency *Currency) bool {
+	switch {
+	case p.symbol == "":
+		return true
+	case strings.EqualFold(p.symbol, currency.Code):
+		return true
+	default:
+		return p.symbol == currency.Symbol
+	}
+}
+
+// FormatPrice renders an amount for display with comma digit grouping. Symbols
+// made of letters ("kr", "CHF") follow the number, others ("€", "$") precede
+// it.
+func FormatPrice(amount Money, currency *Currency) string {
+	s := amount.String()
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	intPart, fracPart, hasPoint := strings.Cut(s, ".")
+
+	var grouped strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(r)
+	}
+
+	number := grouped.String()
+	if hasPoint {
+		number += "." + fracPart
+	}
+
+	symbol := currency.Symbol
+	if strings.IndexFunc(symbol, func(r rune) bool { return !unicode.IsLetter(r) }) < 0 {
+		return sign + number + " " + symbol
+	}
+	return sign + symbol + number
 }
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		amount    string
+		symbol    string
+		thousands bool
+		err       error
+	}{
+		{name: "plain", input: "12.50", amount: "12.50"},
+		{name: "integer", input: "1500", amount: "1500"},
+		{name: "leading symbol", input: "$12.50", amount: "12.50", symbol: "$"},
+		{name: "trailing symbol", input: "12,50 €", amount: "12.50", symbol: "€"},
+		{name: "leading code", input: "EUR 1.234,56", amount: "1234.56", symbol: "EUR"},
+		{name: "trailing code", input: "12.50 USD", amount: "12.50", symbol: "USD"},
+		{name: "space grouping", input: "1 234.5", amount: "1234.5"},
+		{name: "apostrophe grouping", input: "CHF 1'234.50", amount: "1234.50", symbol: "CHF"},
+		{name: "comma grouping", input: "1,234,567.89", amount: "1234567.89"},
+		{name: "dot grouping", input: "1.234.567,89", amount: "1234567.89"},
+		{name: "repeated comma", input: "1,234,567", amount: "1234567"},
+		{name: "repeated dot", input: "1.234.567", amount: "1234567"},
+		{name: "lone comma before three digits", input: "$1,500", amount: "1500", symbol: "$", thousands: true},
+		{name: "lone dot before three digits", input: "2.000 USD", amount: "2000", symbol: "USD", thousands: true},
+		{name: "lone comma before two digits", input: "1,50", amount: "1.50"},
+		{name: "lone dot before four digits", input: "1.5000", amount: "1.5000"},
+		{name: "lakh grouping", input: "₹1,23,456.00", amount: "123456.00", symbol: "₹"},
+		{name: "negative", input: "-$5", amount: "-5", symbol: "$"},
+		{name: "negative after symbol", input: "$-5", amount: "-5", symbol: "$"},
+		{name: "surrounding space", input: "  7  ", amount: "7"},
+		{name: "empty", input: "", err: ErrInvalidPriceFormat},
+		{name: "no digits", input: "USD", err: ErrInvalidPriceFormat},
+		{name: "symbol on both sides", input: "$12 USD", err: ErrInvalidPriceFormat},
+		{name: "letters inside number", input: "12a50", err: ErrInvalidPriceFormat},
+		{name: "double separator", input: "12..50", err: ErrInvalidPriceFormat},
+		{name: "short group", input: "1,23,4", err: ErrInvalidPriceFormat},
+		{name: "separator in fraction", input: "1.234,5.6", err: ErrInvalidPriceFormat},
+		{name: "too many decimals", input: "0.1234567890123456789", err: ErrInvalidPriceFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := ParsePrice(tt.input)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v; want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := price.Amount.String(); got != tt.amount {
+				t.Errorf("got amount %q; want %q", got, tt.amount)
+			}
+			if price.symbol != tt.symbol {
+				t.Errorf("got symbol %q; want %q", price.symbol, tt.symbol)
+			}
+			if price.thousands != tt.thousands {
+				t.Errorf("got thousands %t; want %t", price.thousands, tt.thousands)
+			}
+		})
+	}
+}
+
+func testCurrencies() *CurrencyRegistry {
+	return &CurrencyRegistry{currencies: map[string]*Currency{
+		"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+		"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+		"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+		"BHD": {Code: "BHD", Exponent: 3, Symbol: "BD"},
+	}}
+}
+
+func TestParsePriceCurrencyScale(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     string
+	}{
+		{input: "$1,500", currency: "USD", want: "1500.00"},
+		{input: "USD 2,000", currency: "USD", want: "2000.00"},
+		{input: "1.500 €", currency: "EUR", want: "1500.00"},
+		{input: "¥1,500", currency: "JPY", want: "1500"},
+		{input: "1.500 BD", currency: "BHD", want: "1.500"},
+		{input: "1,500.000 BD", currency: "BHD", want: "1500.000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			price, err := ParsePrice(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+			code := tt.currency
+			validatePrice(v, testCurrencies(), &price, &code)
+
+			if !v.Valid() {
+				t.Fatalf("unexpected errors: %v", v.Errors)
+			}
+			if got := price.Amount.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPriceRoundTrip(t *testing.T) {
+	currencies := testCurrencies()
+
+	tests := []struct {
+		currency string
+		amounts  []string
+	}{
+		{currency: "USD", amounts: []string{"0.99", "12.50", "1500.00", "1234567.89"}},
+		{currency: "EUR", amounts: []string{"0.05", "999.99", "2000.00", "1000000.00"}},
+		{currency: "JPY", amounts: []string{"1", "980", "1500", "2000000"}},
+		{currency: "BHD", amounts: []string{"0.125", "1.500", "1500.000"}},
+	}
+
+	for _, tt := range tests {
+		currency, _ := currencies.Lookup(tt.currency)
+
+		for _, amount := range tt.amounts {
+			t.Run(tt.currency+" "+amount, func(t *testing.T) {
+				want, err := ParseMoney(amount)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				formatted := FormatPrice(want, currency)
+
+				price, err := ParsePrice(formatted)
+				if err != nil {
+					t.Fatalf("parsing %q: %v", formatted, err)
+				}
+
+				v := validator.New()
+				code := tt.currency
+				validatePrice(v, currencies, &price, &code)
+
+				if !v.Valid() {
+					t.Fatalf("%q: unexpected errors: %v", formatted, v.Errors)
+				}
+				if price.Amount.Cmp(want) != 0 || price.Amount.String() != amount {
+					t.Errorf("%q read back as %s; want %s", formatted, price.Amount, amount)
+				}
+			})
+		}
+	}
+}
+
+func TestPriceMatchesCurrency(t *testing.T) {
+	usd := &Currency{Code: "USD", Symbol: "$"}
+	aud := &Currency{Code: "AUD", Symbol: "A$"}
+	brl := &Currency{Code: "BRL", Symbol: "R$"}
+
+	tests := []struct {
+		name     string
+		symbol   string
+		currency *Currency
+		want     bool
+	}{
+		{name: "no symbol", symbol: "", currency: aud, want: true},
+		{name: "exact symbol", symbol: "$", currency: usd, want: true},
+		{name: "prefixed symbol", symbol: "A$", currency: aud, want: true},
+		{name: "bare dollar for A$", symbol: "$", currency: aud, want: false},
+		{name: "bare dollar for R$", symbol: "$", currency: brl, want: false},
+		{name: "other currency symbol", symbol: "A$", currency: usd, want: false},
+		{name: "code", symbol: "AUD", currency: aud, want: true},
+		{name: "lower case code", symbol: "aud", currency: aud, want: true},
+		{name: "other code", symbol: "USD", currency: aud, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := Price{symbol: tt.symbol}
+
+			if got := price.matchesCurrency(tt.currency); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency *Currency
+		want     string
+	}{
+		{name: "symbol before", amount: "12.50", currency: &Currency{Symbol: "€"}, want: "€12.50"},
+		{name: "letters after", amount: "12.50", currency: &Currency{Symbol: "kr"}, want: "12.50 kr"},
+		{name: "grouping", amount: "1234567.00", currency: &Currency{Symbol: "$"}, want: "$1,234,567.00"},
+		{name: "no decimals", amount: "1500", currency: &Currency{Symbol: "¥"}, want: "¥1,500"},
+		{name: "negative", amount: "-1234.5", currency: &Currency{Symbol: "$"}, want: "-$1,234.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ParseMoney(tt.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := FormatPrice(amount, tt.currency); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		amount string
+		symbol string
+		err    bool
+	}{
+		{name: "number", input: `12.5`, amount: "12.5"},
+		{name: "string", input: `"$12.50"`, amount: "12.50", symbol: "$"},
+		{name: "object", input: `{"amount": "12.50", "currency": "EUR"}`, amount: "12.50", symbol: "EUR"},
+		{name: "bad number", input: `1e3`, err: true},
+		{name: "bad string", input: `"twelve"`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var price Price
+
+			err := json.Unmarshal([]byte(tt.input), &price)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := price.Amount.String(); got != tt.amount {
+				t.Errorf("got amount %q; want %q", got, tt.amount)
+			}
+			if price.symbol != tt.symbol {
+				t.Errorf("got symbol %q; want %q", price.symbol, tt.symbol)
+			}
+
+			js, err := json.Marshal(price)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `"` + tt.amount + `"`; string(js) != want {
+				t.Errorf("got JSON %s; want %s", js, want)
+			}
+		})
+	}
+}
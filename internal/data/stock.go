@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+const maxStockQuantity = 1000000000
+
+// StockLevel is the quantity of an item on hand at one location.
+type StockLevel struct {
+	Location     int64     `json:"location"`
+	LocationName string    `json:"locationName"`
+	Quantity     int       `json:"quantity"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	UpdatedBy    *int64    `json:"updatedBy,omitempty"`
+}
+
+// StockSummary totals an item's stock across every location holding some.
+type StockSummary struct {
+	Total     int `json:"total"`
+	Locations int `json:"locations"`
+}
+
+func ValidateStockQuantity(v *validator.Validator, quantity int) {
+	v.Check(quantity >= 0, "quantity", "must not be negative")
+	v.Check(quantity <= maxStockQuantity, "quantity", "must not be more than 1000000000")
+}
+
+type StockModel struct {
+	DB *sql.DB
+}
+
+// GetForItem returns the stock of an item at each location holding some, by
+// location name.
+func (m *StockModel) GetForItem(itemID int64) ([]*StockLevel, error) {
+	qry := `
+		SELECT item_stock.location_id, locations.name, item_stock.quantity, item_stock.updated_at, item_stock.updated_by
+		FROM item_stock
+		JOIN locations ON locations.id = item_stock.location_id
+		WHERE item_stock.item_id = $1
+		ORDER BY lower(locations.name) ASC, locations.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []*StockLevel{}
+
+	for rows.Next() {
+		var level StockLevel
+
+		err := rows.Scan(&level.Location, &level.LocationName, &level.Quantity, &level.UpdatedAt, &level.UpdatedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		levels = append(levels, &level)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+// Summaries returns the stock summary of each of the items, including items
+// with no stock anywhere.
+func (m *StockModel) Summaries(itemIDs []int64) (map[int64]*StockSummary, error) {
+	qry := `
+		SELECT item_id, sum(quantity), count(*)
+		FROM item_stock
+		WHERE item_id = ANY($1)
+		GROUP BY item_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, pq.Array(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[int64]*StockSummary, len(itemIDs))
+	for _, id := range itemIDs {
+		summaries[id] = &StockSummary{}
+	}
+
+	for rows.Next() {
+		var id int64
+		var summary StockSummary
+
+		err := rows.Scan(&id, &summary.Total, &summary.Locations)
+		if err != nil {
+			return nil, err
+		}
+
+		summaries[id] = &summary
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// Set records quantity as the stock of an item at a location, replacing any
+// earlier count. Setting it to zero removes the location from the item's
+// stock.
+func (m *StockModel) Set(itemID, locationID int64, quantity int, userID int64) error {
+	qry := `
+		INSERT INTO item_stock (item_id, location_id, quantity, updated_by)
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+		ON CONFLICT (item_id, location_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity, updated_at = NOW(), updated_by = EXCLUDED.updated_by`
+	args := []any{itemID, locationID, quantity, userID}
+
+	if quantity == 0 {
+		qry = `
+			DELETE FROM item_stock
+			WHERE item_id = $1 AND location_id = $2`
+		args = args[:2]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, args...)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "item_stock" violates foreign key constraint "item_stock_location_id_fkey"`:
+			return ErrUnknownLocation
+		case err.Error() == `pq: insert or update on table "item_stock" violates foreign key constraint "item_stock_item_id_fkey"`:
+			return ErrNoRecord
+		default:
+			return err
+		}
+	}
+
+	if quantity == 0 {
+		// Nothing is deleted when the location held none of the item, which
+		// is fine, but also when it doesn't exist, which isn't.
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			var exists bool
+			err = m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, locationID).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrUnknownLocation
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+var (
+	ErrDuplicateSupplierName = errors.New("duplicate supplier name")
+	ErrSupplierInUse         = errors.New("supplier is referenced by items")
+)
+
+type Supplier struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	ContactName   string    `json:"contactName"`
+	Email         string    `json:"email"`
+	Phone         string    `json:"phone"`
+	AccountNumber string    `json:"accountNumber"`
+	Notes         string    `json:"notes"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Version       int       `json:"version"`
+}
+
+func ValidateSupplier(v *validator.Validator, supplier *Supplier) {
+	v.Check(supplier.Name != "", "name", "must be provided")
+	v.Check(len(supplier.Name) <= 255, "name", "must not be more than 255 characters long")
+	v.Check(len(supplier.ContactName) <= 255, "contactName", "must not be more than 255 characters long")
+	v.Check(len(supplier.Phone) <= 50, "phone", "must not be more than 50 characters long")
+	v.Check(len(supplier.AccountNumber) <= 100, "accountNumber", "must not be more than 100 characters long")
+
+	if supplier.Email != "" {
+		v.Check(validator.Matches(supplier.Email, validator.EmailRX), "email", "must be a valid email address")
+	}
+}
+
+type SupplierModel struct {
+	DB *sql.DB
+}
+
+func (m *SupplierModel) Insert(supplier *Supplier) error {
+	qry := `
+		INSERT INTO suppliers (name, contact_name, email, phone, account_number, notes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone, supplier.AccountNumber, supplier.Notes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&supplier.ID, &supplier.CreatedAt, &supplier.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "suppliers_name_key"`:
+			return ErrDuplicateSupplierName
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *SupplierModel) Get(id int64) (*Supplier, error) {
+	if id < 1 {
+		return nil, ErrNoRecord
+	}
+
+	qry := `
+		SELECT id, name, contact_name, email, phone, account_number, notes, created_at, version
+		FROM suppliers
+		WHERE id = $1`
+
+	var supplier Supplier
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, id).Scan(
+		&supplier.ID,
+		&supplier.Name,
+		&supplier.ContactName,
+		&supplier.Email,
+		&supplier.Phone,
+		&supplier.AccountNumber,
+		&supplier.Notes,
+		&supplier.CreatedAt,
+		&supplier.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+	return &supplier, nil
+}
+
+// GetByName looks a supplier up by its name, ignoring case.
+func (m *SupplierModel) GetByName(name string) (*Supplier, error) {
+	qry := `
+		SELECT id, name, contact_name, email, phone, account_number, notes, created_at, version
+		FROM suppliers
+		WHERE name = $1`
+
+	var supplier Supplier
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, name).Scan(
+		&supplier.ID,
+		&supplier.Name,
+		&supplier.ContactName,
+		&supplier.Email,
+		&supplier.Phone,
+		&supplier.AccountNumber,
+		&supplier.Notes,
+		&supplier.CreatedAt,
+		&supplier.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRecord
+		default:
+			return nil, err
+		}
+	}
+	return &supplier, nil
+}
+
+func (m *SupplierModel) Update(supplier *Supplier) error {
+	qry := `
+		UPDATE suppliers
+		SET name = $1, contact_name = $2, email = $3, phone = $4, account_number = $5, notes = $6, version = version + 1
+		WHERE id = $7 AND version = $8
+		RETURNING version`
+
+	args := []interface{}{
+		supplier.Name,
+		supplier.ContactName,
+		supplier.Email,
+		supplier.Phone,
+		supplier.AccountNumber,
+		supplier.Notes,
+		supplier.ID,
+		supplier.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, qry, args...).Scan(&supplier.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "suppliers_name_key"`:
+			return ErrDuplicateSupplierName
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *SupplierModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrNoRecord
+	}
+
+	qry := `
+		DELETE FROM suppliers
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, qry, id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: update or delete on table "suppliers" violates foreign key constraint "items_supplier_fkey" on table "items"`:
+			return ErrSupplierInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (m *SupplierModel) GetAll(name string, filters Filters) ([]*Supplier, Metadata, error) {
+	qry := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, name, contact_name, email, phone, account_number, notes, created_at, version
+		FROM suppliers
+		WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, likeEscaper.Replace(name), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	suppliers := []*Supplier{}
+
+	for rows.Next() {
+		var supplier Supplier
+		err := rows.Scan(
+			&totalRecords,
+			&supplier.ID,
+			&supplier.Name,
+			&supplier.ContactName,
+			&supplier.Email,
+			&supplier.Phone,
+			&supplier.AccountNumber,
+			&supplier.Notes,
+			&supplier.CreatedAt,
+			&supplier.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		suppliers = append(suppliers, &supplier)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return suppliers, metadata, nil
+}
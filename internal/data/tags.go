@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vmx-pso/item-service/internal/validator"
+)
+
+// TagNormalization holds the rules item tags are normalised by before they
+// are validated and stored.
+type TagNormalization struct {
+	// Trim removes leading and trailing white space.
+	Trim bool
+	// FoldCase lower cases tags so "Cable" and "cable" are the same tag.
+	FoldCase bool
+}
+
+// TagRules are the normalisation rules in force. They are set once at
+// startup.
+var TagRules = TagNormalization{Trim: true}
+
+// NormalizeTag applies TagRules to a single tag.
+func NormalizeTag(tag string) string {
+	if TagRules.Trim {
+		tag = strings.TrimSpace(tag)
+	}
+	if TagRules.FoldCase {
+		tag = strings.ToLower(tag)
+	}
+	return tag
+}
+
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := make([]string, len(tags))
+	for i, tag := range tags {
+		normalized[i] = NormalizeTag(tag)
+	}
+	return normalized
+}
+
+func ValidateTag(v *validator.Validator, key, tag string) {
+	v.Check(tag != "", key, "must be provided")
+	v.Check(len(tag) <= 100, key, "must not be more than 100 characters long")
+}
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// GetAll lists the tags in use with the number of items carrying each,
+// optionally only those starting with prefix, ignoring case. Trashed items
+// aren't counted.
+func (m *TagModel) GetAll(prefix string, filters Filters) ([]*Tag, Metadata, error) {
+	qry := fmt.Sprintf(`
+		SELECT count(*) OVER() AS total_records, tag AS name, count(*) AS count
+		FROM items, unnest(items.tags) AS tag
+		WHERE items.deleted_at IS NULL
+		AND (lower(tag) LIKE $1 OR $1 = '')
+		GROUP BY tag
+		ORDER BY %s %s, name ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	pattern := ""
+	if prefix != "" {
+		pattern = likeEscaper.Replace(strings.ToLower(prefix)) + "%"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, qry, pattern, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tags := []*Tag{}
+
+	for rows.Next() {
+		var tag Tag
+
+		err := rows.Scan(&totalRecords, &tag.Name, &tag.Count)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return tags, metadata, nil
+}
+
+// Rename replaces the tag from with to on every item carrying it. If some
+// items already carry to, the two tags are merged. It returns the number of
+// items changed, or ErrNoRecord if no item carries from.
+func (m *TagModel) Rename(from, to string, userID int64) (int, error) {
+	return m.Merge([]string{from}, to, userID)
+}
+
+// Merge replaces each of the sources with target on every item carrying any
+// of them, keeping a single copy of target in the place of the first tag
+// replaced; other tags, including any unrelated duplicates, are left as they
+// are. The items are rewritten by a single statement that bumps updated_at, so
+// concurrent edits of them fail with ErrEditConflict, and a revision is
+// recorded for each from the rows it returns. Items in the trash keep their
+// tags. It returns the number of items changed, or ErrNoRecord if no item
+// carries any of the sources.
+func (m *TagModel) Merge(sources []string, target string, userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// tags && $1 is answered from items_tags_idx. Each array is rebuilt in
+	// order with the sources mapped to target, then every copy of target after
+	// the first is dropped.
+	qry := `
+		UPDATE items
+		SET tags = (
+			SELECT array_agg(tag ORDER BY ord)
+			FROM (
+				SELECT tag, ord, row_number() OVER (PARTITION BY tag = $2 ORDER BY ord) AS n
+				FROM (
+					SELECT CASE WHEN t = ANY($1) THEN $2::text ELSE t END, ord
+					FROM unnest(items.tags) WITH ORDINALITY AS u(t, ord)
+				) AS mapped(tag, ord)
+			) AS numbered
+			WHERE tag <> $2 OR n = 1
+		), updated_at = NOW()
+		WHERE tags && $1 AND deleted_at IS NULL
+		RETURNING ` + itemListColumns
+
+	rows, err := tx.QueryContext(ctx, qry, pq.Array(sources), target)
+	if err != nil {
+		return 0, err
+	}
+
+	var items []*Item
+
+	for rows.Next() {
+		item, err := scanListItem(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(items) == 0 {
+		return 0, ErrNoRecord
+	}
+
+	err = recordItemRevisions(ctx, tx, items, RevisionUpdate, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(items), nil
+}
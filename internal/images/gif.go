@@ -0,0 +1,87 @@
+package images
+
+import (
+	"encoding/binary"
+)
+
+// gifPixels walks the blocks of a GIF without decoding any image data and
+// returns the total number of pixels in its frames, which is what
+// gif.DecodeAll allocates. It returns ErrInvalidImage if the structure is
+// malformed and stops counting once the total passes MaxPixels.
+func gifPixels(data []byte) (int, error) {
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, ErrInvalidImage
+	}
+
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << ((flags & 0x07) + 1)
+	}
+
+	total := 0
+
+	for {
+		if i >= len(data) {
+			return 0, ErrInvalidImage
+		}
+
+		switch data[i] {
+		case 0x3B: // Trailer.
+			return total, nil
+		case 0x21: // Extension: a label followed by data sub-blocks.
+			if i+2 > len(data) {
+				return 0, ErrInvalidImage
+			}
+
+			next, ok := skipGIFSubBlocks(data, i+2)
+			if !ok {
+				return 0, ErrInvalidImage
+			}
+			i = next
+		case 0x2C: // Image descriptor, then the LZW minimum code size and data.
+			if i+10 > len(data) {
+				return 0, ErrInvalidImage
+			}
+
+			width := int(binary.LittleEndian.Uint16(data[i+5:]))
+			height := int(binary.LittleEndian.Uint16(data[i+7:]))
+
+			total += width * height
+			if total > MaxPixels {
+				return total, nil
+			}
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << ((flags & 0x07) + 1)
+			}
+
+			next, ok := skipGIFSubBlocks(data, i+1)
+			if !ok {
+				return 0, ErrInvalidImage
+			}
+			i = next
+		default:
+			return 0, ErrInvalidImage
+		}
+	}
+}
+
+// skipGIFSubBlocks returns the index just past the sequence of data
+// sub-blocks starting at i, which ends with an empty block.
+func skipGIFSubBlocks(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+
+		if size == 0 {
+			return i, true
+		}
+
+		i += size
+	}
+
+	return 0, false
+}
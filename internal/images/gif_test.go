@@ -0,0 +1,85 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func encodeGIF(t *testing.T, frames int, width, height int) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{Config: image.Config{ColorModel: palette, Width: width, Height: height}}
+
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, width, height), palette))
+		g.Delay = append(g.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+
+	err := gif.EncodeAll(&buf, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestGIFPixels(t *testing.T) {
+	tests := []struct {
+		name          string
+		frames        int
+		width, height int
+		want          int
+	}{
+		{name: "single frame", frames: 1, width: 10, height: 20, want: 200},
+		{name: "animation", frames: 5, width: 10, height: 20, want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pixels, err := gifPixels(encodeGIF(t, tt.frames, tt.width, tt.height))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if pixels != tt.want {
+				t.Errorf("got %d pixels; want %d", pixels, tt.want)
+			}
+		})
+	}
+}
+
+func TestGIFPixelsMalformed(t *testing.T) {
+	data := encodeGIF(t, 2, 10, 10)
+
+	for _, n := range []int{0, 12, 20, len(data) - 1} {
+		_, err := gifPixels(data[:n])
+		if !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("truncated to %d bytes: got error %v; want %v", n, err, ErrInvalidImage)
+		}
+	}
+}
+
+func TestSanitiseGIFFrameLimit(t *testing.T) {
+	// Each frame is small, but together they pass MaxPixels.
+	data := encodeGIF(t, MaxPixels/(1000*1000)+1, 1000, 1000)
+
+	_, err := Sanitise(data)
+	if !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("got error %v; want %v", err, ErrTooManyPixels)
+	}
+
+	img, err := Sanitise(encodeGIF(t, 3, 16, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/gif" {
+		t.Errorf("got content type %q; want image/gif", img.ContentType)
+	}
+}
@@ -0,0 +1,145 @@
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"regexp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrInvalidImage    = errors.New("invalid image data")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size of an image to protect against
+// decompression bombs.
+const MaxPixels = 50_000_000
+
+var nameRX = regexp.MustCompile(`^[0-9a-f]{64}(_[a-z]+)?\.(jpg|png|gif)$`)
+
+var extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+var contentTypes = map[string]string{
+	"jpg": "image/jpeg",
+	"png": "image/png",
+	"gif": "image/gif",
+}
+
+// Image is an uploaded image after it has been decoded and re-encoded. Data
+// holds the re-encoded bytes with all metadata removed and Name is the
+// content-addressed file name "<sha256>.<ext>".
+type Image struct {
+	Data        []byte
+	ContentType string
+	Name        string
+	Width       int
+	Height      int
+}
+
+// ValidName reports whether name is a content-addressed image file name as
+// produced by Sanitise or VariantName.
+func ValidName(name string) bool {
+	return nameRX.MatchString(name)
+}
+
+// ContentType returns the MIME type for a file name produced by Sanitise or
+// VariantName.
+func ContentType(name string) string {
+	return contentTypes[name[len(name)-3:]]
+}
+
+// Sanitise checks the real content type of data by sniffing it rather than
+// trusting the client, decodes it and re-encodes it. Re-encoding drops EXIF
+// and any other metadata; the EXIF orientation of a JPEG is applied to the
+// pixels first so the image still displays the right way up.
+func Sanitise(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	var buf bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+
+		img = orient(img, jpegOrientation(data))
+
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		if err != nil {
+			return nil, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+
+		err = png.Encode(&buf, img)
+		if err != nil {
+			return nil, err
+		}
+	case "image/gif":
+		// Every frame is decoded, so the screen size alone doesn't bound
+		// the memory an animation needs.
+		pixels, err := gifPixels(data)
+		if err != nil {
+			return nil, err
+		}
+		if pixels > MaxPixels {
+			return nil, ErrTooManyPixels
+		}
+
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+
+		err = gif.EncodeAll(&buf, g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	config, _, err = image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:        buf.Bytes(),
+		ContentType: contentType,
+		Name:        hex.EncodeToString(sum[:]) + "." + ext,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Variant is a resized copy of an image that fits within a MaxSize x MaxSize
+// box.
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+var Variants = []Variant{
+	{Name: "thumb", MaxSize: 64},
+	{Name: "small", MaxSize: 256},
+	{Name: "medium", MaxSize: 800},
+}
+
+func LookupVariant(name string) (Variant, bool) {
+	for _, variant := range Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// VariantName returns the file name of a variant of the named image. Variants
+// of GIFs are stored as PNGs since only the first frame is kept.
+func VariantName(name string, variant Variant) string {
+	hash, ext, _ := strings.Cut(name, ".")
+	if ext == "gif" {
+		ext = "png"
+	}
+	return hash + "_" + variant.Name + "." + ext
+}
+
+// Resize scales an image produced by Sanitise down to fit the variant,
+// preserving its aspect ratio. Images that already fit are re-encoded at
+// their original size rather than enlarged.
+func Resize(data []byte, variant Variant) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > variant.MaxSize || h > variant.MaxSize {
+		if w >= h {
+			w, h = variant.MaxSize, h*variant.MaxSize/w
+		} else {
+			w, h = w*variant.MaxSize/h, variant.MaxSize
+		}
+	}
+
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	var buf bytes.Buffer
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	default:
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
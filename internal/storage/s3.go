@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 stores objects in a bucket of an S3 compatible service such as AWS S3 or
+// MinIO. Requests use path-style addressing, "<endpoint>/<bucket>/<key>", and
+// are signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func NewS3(endpoint, region, bucket, accessKey, secretKey string) (*S3, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", endpoint)
+	}
+
+	if bucket == "" {
+		return nil, fmt.Errorf("storage: S3 bucket must be provided")
+	}
+
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+
+	// S3 rejects chunked uploads, so the length has to be known up front.
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	info := s.info(key, res)
+	if info.Size < 0 {
+		return &Object{Info: info, Body: res.Body}, nil
+	}
+
+	body := &s3Body{s: s, ctx: ctx, key: key, size: info.Size, body: res.Body}
+	return &Object{Info: info, Body: body}, nil
+}
+
+// s3Body reads an object and lets it be seeked by reopening it at the new
+// offset with a ranged GET on the next read, so http.ServeContent can answer
+// range requests without the whole object being downloaded first.
+type s3Body struct {
+	s    *S3
+	ctx  context.Context
+	key  string
+	size int64
+
+	// offset is where the next read starts. body, when open, is positioned
+	// at bodyOffset.
+	offset     int64
+	body       io.ReadCloser
+	bodyOffset int64
+}
+
+func (b *s3Body) Read(p []byte) (int, error) {
+	if b.body != nil && b.bodyOffset != b.offset {
+		b.body.Close()
+		b.body = nil
+	}
+
+	if b.body == nil {
+		if b.offset >= b.size {
+			return 0, io.EOF
+		}
+
+		req, err := b.s.newRequest(b.ctx, http.MethodGet, b.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+
+		res, err := b.s.do(req)
+		if err != nil {
+			return 0, err
+		}
+
+		if res.StatusCode != http.StatusPartialContent {
+			res.Body.Close()
+			return 0, fmt.Errorf("storage: S3 GET %s ignored the range, responding %s", req.URL.Path, res.Status)
+		}
+
+		b.body = res.Body
+		b.bodyOffset = b.offset
+	}
+
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	b.bodyOffset += int64(n)
+
+	return n, err
+}
+
+func (b *s3Body) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *s3Body) Close() error {
+	if b.body == nil {
+		return nil
+	}
+
+	err := b.body.Close()
+	b.body = nil
+	return err
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (*Info, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+
+	info := s.info(key, res)
+	return &info, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + "/" + uriEncode(s.bucket) + "/" + uriEncode(key)
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req, turning error responses into Go errors. The caller
+// must close the body of a successful response.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return nil, fmt.Errorf("storage: S3 %s %s: %s: %s", req.Method, req.URL.Path, res.Status, strings.TrimSpace(string(msg)))
+}
+
+func (s *S3) info(key string, res *http.Response) Info {
+	info := Info{
+		Key:         key,
+		Size:        res.ContentLength,
+		ContentType: res.Header.Get("Content-Type"),
+	}
+
+	if size, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = size
+	}
+
+	if t, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+
+	return info
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req. The
+// payload is left unsigned so bodies can be streamed rather than hashed up
+// front.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// uriEncode escapes everything except the unreserved characters and '/', as
+// Signature Version 4 requires for S3 object paths.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
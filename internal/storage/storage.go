@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Store is a flat namespace of blobs addressed by slash separated keys such
+// as "images/ab/ab12...jpg".
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any existing
+	// object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key. The caller must close the
+	// returned Object. The body also implements io.Seeker when the backend
+	// supports it.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Stat returns the object's metadata without reading it.
+	Stat(ctx context.Context, key string) (*Info, error)
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+type Object struct {
+	Info
+	Body io.ReadCloser
+}
+
+func (o *Object) Close() error {
+	return o.Body.Close()
+}
+
+// Exists reports whether an object is stored under key.
+func Exists(ctx context.Context, s Store, key string) (bool, error) {
+	_, err := s.Stat(ctx, key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// ReadAll returns the full contents of the object stored under key.
+func ReadAll(ctx context.Context, s Store, key string) ([]byte, error) {
+	obj, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(obj.Body)
+}
+
+// validKey rejects keys that could escape the store's root or that backends
+// would interpret differently.
+func validKey(key string) bool {
+	if key == "" || key[0] == '/' || key[len(key)-1] == '/' {
+		return false
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	for _, r := range key {
+		if r < 0x20 || r == 0x7f || r == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,184 @@
+// Package xlsx streams a single worksheet in the Office Open XML spreadsheet
+// format. Rows are written straight through to the underlying writer, so the
+// size of a workbook doesn't affect memory use. Only the features needed for
+// data exports are supported: text, numbers and booleans, with no styles.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number is a cell value written as a number. Holding it as a string keeps
+// exact decimal values, such as prices, exact.
+type Number string
+
+var ErrClosed = errors.New("xlsx: writer is closed")
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+type Writer struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	row    int
+	closed bool
+}
+
+// NewWriter starts a workbook with a single sheet of the given name.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+
+	for _, part := range parts {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.WriteString(f, part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The worksheet is the last part of the archive so its rows can be
+	// streamed into it.
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "xl/worksheets/sheet1.xml", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := bufio.NewWriter(f)
+
+	_, err = sheet.WriteString(sheetHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Cells may be strings, Numbers, bools, ints, int64s
+// or float64s; nil leaves the cell empty.
+func (w *Writer) WriteRow(cells ...any) error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	w.row++
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<row r="%d">`, w.row)
+
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(w.row)
+
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case string:
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(v))
+		case Number:
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, escape(string(v)))
+		case bool:
+			value := "0"
+			if v {
+				value = "1"
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="b"><v>%s</v></c>`, ref, value)
+		case int:
+			fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return fmt.Errorf("xlsx: unsupported cell type %T", cell)
+		}
+	}
+
+	b.WriteString(`</row>`)
+
+	_, err := w.sheet.WriteString(b.String())
+	return err
+}
+
+// Flush writes any buffered rows through to the underlying writer. The zip
+// compressor may still hold some data back until more rows are written.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	return w.sheet.Flush()
+}
+
+// Close finishes the worksheet and the archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+
+	_, err := w.sheet.WriteString(sheetFooter)
+	if err != nil {
+		return err
+	}
+
+	err = w.sheet.Flush()
+	if err != nil {
+		return err
+	}
+
+	return w.zw.Close()
+}
+
+// columnName converts a zero-based column index into its spreadsheet name:
+// A, B, ..., Z, AA, AB and so on.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS items_supplier_idx;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_supplier_fkey;
+ALTER TABLE items ALTER COLUMN supplier TYPE integer;
+DROP TABLE IF EXISTS suppliers;
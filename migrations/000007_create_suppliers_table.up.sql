@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+    id bigserial PRIMARY KEY,
+    name citext UNIQUE NOT NULL,
+    contact_name text NOT NULL DEFAULT '',
+    email citext NOT NULL DEFAULT '',
+    phone text NOT NULL DEFAULT '',
+    account_number text NOT NULL DEFAULT '',
+    notes text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+-- Create a placeholder supplier for every id already referenced by an item so
+-- the foreign key below can be added to existing data.
+INSERT INTO suppliers (id, name)
+SELECT DISTINCT supplier, 'Supplier ' || supplier
+FROM items
+ON CONFLICT DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('suppliers', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM suppliers;
+
+ALTER TABLE items ALTER COLUMN supplier TYPE bigint;
+ALTER TABLE items ADD CONSTRAINT items_supplier_fkey FOREIGN KEY (supplier) REFERENCES suppliers (id);
+CREATE INDEX IF NOT EXISTS items_supplier_idx ON items (supplier);
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE code IN ('suppliers:read', 'suppliers:write');
@@ -0,0 +1,4 @@
+INSERT INTO permissions (code)
+VALUES
+    ('suppliers:read'),
+    ('suppliers:write');
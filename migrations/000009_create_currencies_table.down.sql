@@ -0,0 +1,2 @@
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_currency_fkey;
+DROP TABLE IF EXISTS currencies;
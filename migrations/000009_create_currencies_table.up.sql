@@ -0,0 +1,174 @@
+-- Currencies are keyed by their ISO 4217 numeric code so that the existing
+-- integer items.currency column can reference them directly.
+CREATE TABLE IF NOT EXISTS currencies (
+    id integer PRIMARY KEY,
+    code char(3) UNIQUE NOT NULL,
+    name text NOT NULL,
+    exponent smallint NOT NULL CHECK (exponent BETWEEN 0 AND 4),
+    symbol text NOT NULL
+);
+
+INSERT INTO currencies (id, code, name, exponent, symbol)
+VALUES
+    (784, 'AED', 'UAE Dirham', 2, 'د.إ'),
+    (971, 'AFN', 'Afghani', 2, '؋'),
+    (8, 'ALL', 'Lek', 2, 'L'),
+    (51, 'AMD', 'Armenian Dram', 2, '֏'),
+    (532, 'ANG', 'Netherlands Antillean Guilder', 2, 'ƒ'),
+    (973, 'AOA', 'Kwanza', 2, 'Kz'),
+    (32, 'ARS', 'Argentine Peso', 2, '$'),
+    (36, 'AUD', 'Australian Dollar', 2, 'A$'),
+    (533, 'AWG', 'Aruban Florin', 2, 'ƒ'),
+    (944, 'AZN', 'Azerbaijan Manat', 2, '₼'),
+    (977, 'BAM', 'Convertible Mark', 2, 'KM'),
+    (52, 'BBD', 'Barbados Dollar', 2, '$'),
+    (50, 'BDT', 'Taka', 2, '৳'),
+    (975, 'BGN', 'Bulgarian Lev', 2, 'лв'),
+    (48, 'BHD', 'Bahraini Dinar', 3, 'BD'),
+    (108, 'BIF', 'Burundi Franc', 0, 'FBu'),
+    (60, 'BMD', 'Bermudian Dollar', 2, '$'),
+    (96, 'BND', 'Brunei Dollar', 2, '$'),
+    (68, 'BOB', 'Boliviano', 2, 'Bs.'),
+    (986, 'BRL', 'Brazilian Real', 2, 'R$'),
+    (44, 'BSD', 'Bahamian Dollar', 2, '$'),
+    (64, 'BTN', 'Ngultrum', 2, 'Nu.'),
+    (72, 'BWP', 'Pula', 2, 'P'),
+    (933, 'BYN', 'Belarusian Ruble', 2, 'Br'),
+    (84, 'BZD', 'Belize Dollar', 2, '$'),
+    (124, 'CAD', 'Canadian Dollar', 2, 'CA$'),
+    (976, 'CDF', 'Congolese Franc', 2, 'FC'),
+    (756, 'CHF', 'Swiss Franc', 2, 'CHF'),
+    (990, 'CLF', 'Unidad de Fomento', 4, 'UF'),
+    (152, 'CLP', 'Chilean Peso', 0, '$'),
+    (156, 'CNY', 'Yuan Renminbi', 2, 'CN¥'),
+    (170, 'COP', 'Colombian Peso', 2, '$'),
+    (188, 'CRC', 'Costa Rican Colon', 2, '₡'),
+    (192, 'CUP', 'Cuban Peso', 2, '$'),
+    (132, 'CVE', 'Cabo Verde Escudo', 2, '$'),
+    (203, 'CZK', 'Czech Koruna', 2, 'Kč'),
+    (262, 'DJF', 'Djibouti Franc', 0, 'Fdj'),
+    (208, 'DKK', 'Danish Krone', 2, 'kr'),
+    (214, 'DOP', 'Dominican Peso', 2, 'RD$'),
+    (12, 'DZD', 'Algerian Dinar', 2, 'DA'),
+    (818, 'EGP', 'Egyptian Pound', 2, 'E£'),
+    (232, 'ERN', 'Nakfa', 2, 'Nfk'),
+    (230, 'ETB', 'Ethiopian Birr', 2, 'Br'),
+    (978, 'EUR', 'Euro', 2, '€'),
+    (242, 'FJD', 'Fiji Dollar', 2, 'FJ$'),
+    (238, 'FKP', 'Falkland Islands Pound', 2, '£'),
+    (826, 'GBP', 'Pound Sterling', 2, '£'),
+    (981, 'GEL', 'Lari', 2, '₾'),
+    (936, 'GHS', 'Ghana Cedi', 2, 'GH₵'),
+    (292, 'GIP', 'Gibraltar Pound', 2, '£'),
+    (270, 'GMD', 'Dalasi', 2, 'D'),
+    (324, 'GNF', 'Guinean Franc', 0, 'FG'),
+    (320, 'GTQ', 'Quetzal', 2, 'Q'),
+    (328, 'GYD', 'Guyana Dollar', 2, '$'),
+    (344, 'HKD', 'Hong Kong Dollar', 2, 'HK$'),
+    (340, 'HNL', 'Lempira', 2, 'L'),
+    (332, 'HTG', 'Gourde', 2, 'G'),
+    (348, 'HUF', 'Forint', 2, 'Ft'),
+    (360, 'IDR', 'Rupiah', 2, 'Rp'),
+    (376, 'ILS', 'New Israeli Sheqel', 2, '₪'),
+    (356, 'INR', 'Indian Rupee', 2, '₹'),
+    (368, 'IQD', 'Iraqi Dinar', 3, 'IQD'),
+    (364, 'IRR', 'Iranian Rial', 2, 'IRR'),
+    (352, 'ISK', 'Iceland Krona', 0, 'kr'),
+    (388, 'JMD', 'Jamaican Dollar', 2, 'J$'),
+    (400, 'JOD', 'Jordanian Dinar', 3, 'JD'),
+    (392, 'JPY', 'Yen', 0, '¥'),
+    (404, 'KES', 'Kenyan Shilling', 2, 'KSh'),
+    (417, 'KGS', 'Som', 2, 'KGS'),
+    (116, 'KHR', 'Riel', 2, '៛'),
+    (174, 'KMF', 'Comorian Franc', 0, 'CF'),
+    (408, 'KPW', 'North Korean Won', 2, 'KPW'),
+    (410, 'KRW', 'Won', 0, '₩'),
+    (414, 'KWD', 'Kuwaiti Dinar', 3, 'KD'),
+    (136, 'KYD', 'Cayman Islands Dollar', 2, '$'),
+    (398, 'KZT', 'Tenge', 2, '₸'),
+    (418, 'LAK', 'Lao Kip', 2, '₭'),
+    (422, 'LBP', 'Lebanese Pound', 2, 'LBP'),
+    (144, 'LKR', 'Sri Lanka Rupee', 2, 'Rs'),
+    (430, 'LRD', 'Liberian Dollar', 2, '$'),
+    (426, 'LSL', 'Loti', 2, 'L'),
+    (434, 'LYD', 'Libyan Dinar', 3, 'LD'),
+    (504, 'MAD', 'Moroccan Dirham', 2, 'MAD'),
+    (498, 'MDL', 'Moldovan Leu', 2, 'L'),
+    (969, 'MGA', 'Malagasy Ariary', 2, 'Ar'),
+    (807, 'MKD', 'Denar', 2, 'ден'),
+    (104, 'MMK', 'Kyat', 2, 'K'),
+    (496, 'MNT', 'Tugrik', 2, '₮'),
+    (446, 'MOP', 'Pataca', 2, 'MOP$'),
+    (929, 'MRU', 'Ouguiya', 2, 'UM'),
+    (480, 'MUR', 'Mauritius Rupee', 2, 'Rs'),
+    (462, 'MVR', 'Rufiyaa', 2, 'Rf'),
+    (454, 'MWK', 'Malawi Kwacha', 2, 'MK'),
+    (484, 'MXN', 'Mexican Peso', 2, 'MX$'),
+    (458, 'MYR', 'Malaysian Ringgit', 2, 'RM'),
+    (943, 'MZN', 'Mozambique Metical', 2, 'MT'),
+    (516, 'NAD', 'Namibia Dollar', 2, '$'),
+    (566, 'NGN', 'Naira', 2, '₦'),
+    (558, 'NIO', 'Cordoba Oro', 2, 'C$'),
+    (578, 'NOK', 'Norwegian Krone', 2, 'kr'),
+    (524, 'NPR', 'Nepalese Rupee', 2, 'Rs'),
+    (554, 'NZD', 'New Zealand Dollar', 2, 'NZ$'),
+    (512, 'OMR', 'Rial Omani', 3, 'OMR'),
+    (590, 'PAB', 'Balboa', 2, 'B/.'),
+    (604, 'PEN', 'Sol', 2, 'S/'),
+    (598, 'PGK', 'Kina', 2, 'K'),
+    (608, 'PHP', 'Philippine Peso', 2, '₱'),
+    (586, 'PKR', 'Pakistan Rupee', 2, 'Rs'),
+    (985, 'PLN', 'Zloty', 2, 'zł'),
+    (600, 'PYG', 'Guarani', 0, '₲'),
+    (634, 'QAR', 'Qatari Rial', 2, 'QR'),
+    (946, 'RON', 'Romanian Leu', 2, 'lei'),
+    (941, 'RSD', 'Serbian Dinar', 2, 'RSD'),
+    (643, 'RUB', 'Russian Ruble', 2, '₽'),
+    (646, 'RWF', 'Rwanda Franc', 0, 'FRw'),
+    (682, 'SAR', 'Saudi Riyal', 2, 'SR'),
+    (90, 'SBD', 'Solomon Islands Dollar', 2, 'SI$'),
+    (690, 'SCR', 'Seychelles Rupee', 2, 'SR'),
+    (938, 'SDG', 'Sudanese Pound', 2, 'SDG'),
+    (752, 'SEK', 'Swedish Krona', 2, 'kr'),
+    (702, 'SGD', 'Singapore Dollar', 2, 'S$'),
+    (654, 'SHP', 'Saint Helena Pound', 2, '£'),
+    (925, 'SLE', 'Leone', 2, 'Le'),
+    (706, 'SOS', 'Somali Shilling', 2, 'Sh'),
+    (968, 'SRD', 'Surinam Dollar', 2, '$'),
+    (728, 'SSP', 'South Sudanese Pound', 2, 'SSP'),
+    (930, 'STN', 'Dobra', 2, 'Db'),
+    (222, 'SVC', 'El Salvador Colon', 2, '₡'),
+    (760, 'SYP', 'Syrian Pound', 2, 'SYP'),
+    (748, 'SZL', 'Lilangeni', 2, 'E'),
+    (764, 'THB', 'Baht', 2, '฿'),
+    (972, 'TJS', 'Somoni', 2, 'SM'),
+    (934, 'TMT', 'Turkmenistan New Manat', 2, 'm'),
+    (788, 'TND', 'Tunisian Dinar', 3, 'DT'),
+    (776, 'TOP', 'Pa''anga', 2, 'T$'),
+    (949, 'TRY', 'Turkish Lira', 2, '₺'),
+    (780, 'TTD', 'Trinidad and Tobago Dollar', 2, 'TT$'),
+    (901, 'TWD', 'New Taiwan Dollar', 2, 'NT$'),
+    (834, 'TZS', 'Tanzanian Shilling', 2, 'TSh'),
+    (980, 'UAH', 'Hryvnia', 2, '₴'),
+    (800, 'UGX', 'Uganda Shilling', 0, 'USh'),
+    (840, 'USD', 'US Dollar', 2, '$'),
+    (858, 'UYU', 'Peso Uruguayo', 2, '$U'),
+    (860, 'UZS', 'Uzbekistan Sum', 2, 'UZS'),
+    (928, 'VES', 'Bolivar Soberano', 2, 'Bs.S'),
+    (704, 'VND', 'Dong', 0, '₫'),
+    (548, 'VUV', 'Vatu', 0, 'VT'),
+    (882, 'WST', 'Tala', 2, 'WS$'),
+    (950, 'XAF', 'CFA Franc BEAC', 0, 'FCFA'),
+    (951, 'XCD', 'East Caribbean Dollar', 2, 'EC$'),
+    (952, 'XOF', 'CFA Franc BCEAO', 0, 'CFA'),
+    (953, 'XPF', 'CFP Franc', 0, '₣'),
+    (886, 'YER', 'Yemeni Rial', 2, 'YER'),
+    (710, 'ZAR', 'Rand', 2, 'R'),
+    (967, 'ZMW', 'Zambian Kwacha', 2, 'ZK'),
+    (924, 'ZWG', 'Zimbabwe Gold', 2, 'ZiG');
+
+-- Existing rows may hold currency values that predate the registry, so the
+-- constraint is only enforced for new and updated rows. Run
+-- ALTER TABLE items VALIDATE CONSTRAINT items_currency_fkey once they have
+-- been mapped to ISO 4217 numeric codes.
+ALTER TABLE items ADD CONSTRAINT items_currency_fkey FOREIGN KEY (currency) REFERENCES currencies (id) NOT VALID;
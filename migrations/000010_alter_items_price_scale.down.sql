@@ -0,0 +1 @@
+ALTER TABLE items ALTER COLUMN price TYPE numeric(64);
@@ -0,0 +1,5 @@
+-- numeric(64) has a scale of zero, so fractional amounts were being rounded
+-- away. Existing values are whole numbers and convert without loss. Four
+-- decimal places covers every ISO 4217 minor unit and eighteen digits of
+-- precision matches the range of data.Money.
+ALTER TABLE items ALTER COLUMN price TYPE numeric(18, 4);
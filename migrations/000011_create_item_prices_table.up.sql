@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS item_prices (
+    id bigserial PRIMARY KEY,
+    item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+    amount numeric(18, 4) NOT NULL,
+    currency integer NOT NULL,
+    effective_from timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    changed_by bigint REFERENCES users ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS item_prices_item_id_effective_from_idx ON item_prices (item_id, effective_from DESC, id DESC);
+
+-- Seed the history with the current price of every existing item.
+INSERT INTO item_prices (item_id, amount, currency, effective_from)
+SELECT id, price, currency, created_at
+FROM items;
+
+ALTER TABLE item_prices ADD CONSTRAINT item_prices_currency_fkey FOREIGN KEY (currency) REFERENCES currencies (id) NOT VALID;
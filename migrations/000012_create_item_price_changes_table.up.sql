@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS item_price_changes (
+    id bigserial PRIMARY KEY,
+    item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+    amount numeric(18, 4) NOT NULL CHECK (amount > 0),
+    currency integer NOT NULL REFERENCES currencies (id),
+    effective_at timestamp(0) with time zone NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'cancelled')),
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    applied_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS item_price_changes_item_id_idx ON item_price_changes (item_id);
+CREATE INDEX IF NOT EXISTS item_price_changes_pending_idx ON item_price_changes (effective_at) WHERE status = 'pending';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS item_attachments (
+    id bigserial PRIMARY KEY,
+    item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL CHECK (size > 0),
+    checksum char(64) NOT NULL,
+    storage_key text NOT NULL UNIQUE,
+    uploaded_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS item_attachments_item_id_idx ON item_attachments (item_id);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id bigserial PRIMARY KEY,
+    item_id bigint NOT NULL,
+    revision integer NOT NULL,
+    action text NOT NULL CHECK (action IN ('create', 'update', 'archive', 'unarchive', 'restore', 'delete')),
+    snapshot jsonb NOT NULL,
+    changed_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (item_id, revision)
+);
+
+-- Revisions are kept after an item is deleted, so item_id is deliberately not
+-- a foreign key. Existing items start with a single "create" revision.
+INSERT INTO item_revisions (item_id, revision, action, snapshot, created_at)
+SELECT id, 1, 'create', jsonb_build_object(
+    'id', id,
+    'name', name,
+    'model', model,
+    'supplier', supplier,
+    'price', round(price, COALESCE((SELECT exponent FROM currencies WHERE currencies.id = items.currency), 4))::text,
+    'currency', COALESCE((SELECT code FROM currencies WHERE currencies.id = items.currency), ''),
+    'image', image_file,
+    'notes', notes,
+    'tags', to_jsonb(tags),
+    'createdAt', created_at,
+    'updatedAt', updated_at,
+    'archived', archived
+), created_at
+FROM items;
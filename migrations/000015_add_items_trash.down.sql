@@ -0,0 +1,8 @@
+ALTER TABLE item_revisions DROP CONSTRAINT IF EXISTS item_revisions_action_check;
+ALTER TABLE item_revisions ADD CONSTRAINT item_revisions_action_check
+    CHECK (action IN ('create', 'update', 'archive', 'unarchive', 'restore', 'delete')) NOT VALID;
+
+DROP INDEX IF EXISTS items_deleted_at_idx;
+
+ALTER TABLE items DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE items DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS deleted_by bigint REFERENCES users ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS items_deleted_at_idx ON items (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE item_revisions DROP CONSTRAINT IF EXISTS item_revisions_action_check;
+ALTER TABLE item_revisions ADD CONSTRAINT item_revisions_action_check
+    CHECK (action IN ('create', 'update', 'archive', 'unarchive', 'restore', 'delete', 'undelete', 'purge'));
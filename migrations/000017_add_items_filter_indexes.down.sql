@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS items_updated_at_idx;
+DROP INDEX IF EXISTS items_created_at_idx;
+DROP INDEX IF EXISTS items_currency_price_idx;
+DROP INDEX IF EXISTS items_model_prefix_idx;
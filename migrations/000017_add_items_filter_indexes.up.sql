@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS items_model_prefix_idx ON items (lower(model) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS items_currency_price_idx ON items (currency, price);
+CREATE INDEX IF NOT EXISTS items_created_at_idx ON items (created_at);
+CREATE INDEX IF NOT EXISTS items_updated_at_idx ON items (updated_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_search_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS search;
+DROP FUNCTION IF EXISTS items_search_vector(regconfig, text, text, text[], text);
@@ -0,0 +1,20 @@
+-- array_to_string is only stable, so it can't appear in a generated column
+-- directly; wrapping the whole vector in an immutable function is safe here
+-- because the function is only ever given text arrays.
+CREATE OR REPLACE FUNCTION items_search_vector(config regconfig, name text, model text, tags text[], notes text)
+RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT setweight(to_tsvector(config, COALESCE(name, '')), 'A')
+        || setweight(to_tsvector(config, COALESCE(model, '')), 'B')
+        || setweight(to_tsvector(config, COALESCE(array_to_string(tags, ' '), '')), 'C')
+        || setweight(to_tsvector(config, COALESCE(notes, '')), 'D')
+$$;
+
+-- The vector holds the lexemes of every configuration in data.SearchLanguages
+-- so a search can be parsed with any of them.
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    items_search_vector('simple', name, model, tags, notes) || items_search_vector('english', name, model, tags, notes)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS items_search_idx ON items USING GIN (search);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS items_name_prefix_idx;
+DROP INDEX IF EXISTS items_model_trgm_idx;
+DROP INDEX IF EXISTS items_name_trgm_idx;
+DROP EXTENSION IF EXISTS pg_trgm;
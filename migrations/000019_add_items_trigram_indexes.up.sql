@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS items_name_trgm_idx ON items USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS items_model_trgm_idx ON items USING GIN (model gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS items_name_prefix_idx ON items (lower(name) text_pattern_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_category_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS category;
+DROP TABLE IF EXISTS categories;
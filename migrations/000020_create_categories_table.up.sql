@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    parent_id bigint REFERENCES categories,
+    position integer NOT NULL DEFAULT 0,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT categories_parent_check CHECK (parent_id <> id)
+);
+
+-- Sibling names are unique, ignoring case; top level categories share the
+-- parent 0.
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_key ON categories (COALESCE(parent_id, 0), lower(name));
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id, position);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS category bigint REFERENCES categories;
+
+CREATE INDEX IF NOT EXISTS items_category_idx ON items (category);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    type text NOT NULL,
+    unit text NOT NULL DEFAULT '',
+    required boolean NOT NULL DEFAULT false,
+    enum_values text[] NOT NULL DEFAULT '{}',
+    min numeric,
+    max numeric,
+    category bigint REFERENCES categories,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT attribute_definitions_type_check CHECK (type IN ('text', 'number', 'integer', 'boolean', 'date', 'enum')),
+    CONSTRAINT attribute_definitions_range_check CHECK (min <= max)
+);
+
+-- A name is defined once per category, and once globally under category 0.
+-- Subcategories may redefine an attribute of an ancestor.
+CREATE UNIQUE INDEX IF NOT EXISTS attribute_definitions_category_name_key ON attribute_definitions (COALESCE(category, 0), name);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS attributes jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS items_attributes_idx ON items USING GIN (attributes jsonb_path_ops);
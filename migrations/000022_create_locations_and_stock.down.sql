@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'stock:write';
+DROP TABLE IF EXISTS item_stock;
+DROP TABLE IF EXISTS locations;
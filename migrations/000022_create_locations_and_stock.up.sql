@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS locations (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    kind text NOT NULL,
+    parent_id bigint REFERENCES locations,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT locations_kind_check CHECK (kind IN ('warehouse', 'room', 'bin')),
+    CONSTRAINT locations_parent_check CHECK (parent_id <> id)
+);
+
+-- Sibling names are unique, ignoring case; top level locations share the
+-- parent 0.
+CREATE UNIQUE INDEX IF NOT EXISTS locations_parent_name_key ON locations (COALESCE(parent_id, 0), lower(name));
+CREATE INDEX IF NOT EXISTS locations_parent_id_idx ON locations (parent_id);
+
+-- A row holds the quantity of an item on hand at a location. Locations
+-- without any of an item have no row rather than a zero quantity.
+CREATE TABLE IF NOT EXISTS item_stock (
+    item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+    location_id bigint NOT NULL REFERENCES locations,
+    quantity integer NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_by bigint REFERENCES users ON DELETE SET NULL,
+    PRIMARY KEY (item_id, location_id),
+    CONSTRAINT item_stock_quantity_check CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS item_stock_location_id_idx ON item_stock (location_id);
+
+INSERT INTO permissions (code)
+VALUES
+    ('stock:write');
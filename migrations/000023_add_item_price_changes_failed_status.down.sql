@@ -0,0 +1,5 @@
+UPDATE item_price_changes SET status = 'cancelled' WHERE status = 'failed';
+
+ALTER TABLE item_price_changes DROP CONSTRAINT IF EXISTS item_price_changes_status_check;
+ALTER TABLE item_price_changes ADD CONSTRAINT item_price_changes_status_check
+    CHECK (status IN ('pending', 'applied', 'cancelled'));
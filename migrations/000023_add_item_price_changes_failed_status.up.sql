@@ -0,0 +1,3 @@
+ALTER TABLE item_price_changes DROP CONSTRAINT IF EXISTS item_price_changes_status_check;
+ALTER TABLE item_price_changes ADD CONSTRAINT item_price_changes_status_check
+    CHECK (status IN ('pending', 'applied', 'cancelled', 'failed'));